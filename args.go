@@ -0,0 +1,151 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// Argument types.
+const (
+	// argInt is a positive integer.
+	argInt = "int"
+	// argString is a non empty string.
+	argString = "string"
+	// argFiles is a list of one or more existing file paths. It can only be
+	// the last argument of a schema as it takes every remaining argument.
+	argFiles = "files"
+)
+
+// argSpec describes a positional argument taken by an experiment.
+type argSpec struct {
+	Name     string `json:"name"`
+	Type     string `json:"type"`
+	Required bool   `json:"required"`
+	Default  string `json:"default,omitempty"`
+	Help     string `json:"help"`
+}
+
+// expArgs holds the values of an experiment's arguments by name as parsed
+// according to its schema.
+type expArgs map[string]any
+
+// Int returns the value of the int argument name.
+func (a expArgs) Int(name string) int {
+	v, _ := a[name].(int)
+	return v
+}
+
+// String returns the value of the string argument name.
+func (a expArgs) String(name string) string {
+	v, _ := a[name].(string)
+	return v
+}
+
+// Files returns the value of the files argument name.
+func (a expArgs) Files(name string) []string {
+	v, _ := a[name].([]string)
+	return v
+}
+
+// parseArgs validates args against schema and returns their typed values.
+func parseArgs(schema []argSpec, args []string) (expArgs, error) {
+	parsed := make(expArgs)
+
+	for i, spec := range schema {
+		var raw []string
+		switch {
+		case spec.Type == argFiles && i < len(args):
+			raw = args[i:]
+		case i < len(args):
+			raw = args[i : i+1]
+		case spec.Required:
+			return nil, fmt.Errorf("Missing required argument '%s'", spec.Name)
+		case spec.Default != "":
+			raw = strings.Split(spec.Default, ",")
+		}
+
+		val, err := parseArg(spec, raw)
+		if err != nil {
+			return nil, err
+		}
+		parsed[spec.Name] = val
+	}
+
+	if len(schema) == 0 || schema[len(schema)-1].Type != argFiles {
+		if len(args) > len(schema) {
+			return nil, fmt.Errorf(
+				"Too many arguments: expected at most %d but got %d",
+				len(schema),
+				len(args),
+			)
+		}
+	}
+
+	return parsed, nil
+}
+
+// parseArg returns the value of the raw argument values according to spec.
+func parseArg(spec argSpec, raw []string) (any, error) {
+	switch spec.Type {
+	case argInt:
+		if len(raw) == 0 {
+			return 0, nil
+		}
+		v, err := strconv.Atoi(raw[0])
+		if err != nil || v <= 0 {
+			return nil, fmt.Errorf(
+				"Argument '%s': expected a positive integer but got '%s'",
+				spec.Name,
+				raw[0],
+			)
+		}
+		return v, nil
+	case argString:
+		if len(raw) == 0 {
+			return "", nil
+		}
+		if strings.TrimSpace(raw[0]) == "" {
+			return nil, fmt.Errorf(
+				"Argument '%s': expected a non empty string",
+				spec.Name,
+			)
+		}
+		return raw[0], nil
+	case argFiles:
+		for _, f := range raw {
+			if _, err := os.Stat(f); err != nil {
+				return nil, fmt.Errorf(
+					"Argument '%s': file '%s' does not exist",
+					spec.Name,
+					f,
+				)
+			}
+		}
+		return raw, nil
+	default:
+		return nil, fmt.Errorf(
+			"Argument '%s': unknown type '%s'",
+			spec.Name,
+			spec.Type,
+		)
+	}
+}
+
+// argsHelp returns a human readable description of schema.
+func argsHelp(schema []argSpec) string {
+	var sb strings.Builder
+	sb.WriteString("Arguments:\n")
+	for _, spec := range schema {
+		fmt.Fprintf(&sb, "  - %s (%s", spec.Name, spec.Type)
+		if !spec.Required {
+			sb.WriteString(", optional")
+		}
+		if spec.Default != "" {
+			fmt.Fprintf(&sb, ", default %s", spec.Default)
+		}
+		fmt.Fprintf(&sb, "): %s\n", spec.Help)
+	}
+	return sb.String()
+}
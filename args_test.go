@@ -0,0 +1,162 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestParseArgs(t *testing.T) {
+	dir := t.TempDir()
+	f1, f2 := filepath.Join(dir, "t1.json"), filepath.Join(dir, "t2.json")
+	for _, f := range []string{f1, f2} {
+		if err := os.WriteFile(f, []byte("{}"), 0o644); err != nil {
+			t.Fatalf("Failed to write file: %s", err.Error())
+		}
+	}
+
+	schema := []argSpec{
+		{Name: "n", Type: argInt, Required: true},
+		{Name: "query", Type: argString, Required: true},
+		{Name: "limit", Type: argInt, Default: "5"},
+		{Name: "trees", Type: argFiles, Default: f1 + "," + f2},
+	}
+
+	tests := []struct {
+		name     string
+		schema   []argSpec
+		args     []string
+		expected expArgs
+	}{
+		{
+			name:   "all given",
+			schema: schema,
+			args:   []string{"3", "sr", "2", f2, f1},
+			expected: expArgs{
+				"n":     3,
+				"query": "sr",
+				"limit": 2,
+				"trees": []string{f2, f1},
+			},
+		},
+		{
+			name:   "defaults",
+			schema: schema,
+			args:   []string{"3", "sr"},
+			expected: expArgs{
+				"n":     3,
+				"query": "sr",
+				"limit": 5,
+				"trees": []string{f1, f2},
+			},
+		},
+		{
+			name: "optional without default",
+			schema: []argSpec{
+				{Name: "n", Type: argInt},
+				{Name: "query", Type: argString},
+			},
+			args:     []string{},
+			expected: expArgs{"n": 0, "query": ""},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			parsed, err := parseArgs(test.schema, test.args)
+			if err != nil {
+				t.Fatalf("Failed to parse arguments: %s", err.Error())
+			}
+			if !reflect.DeepEqual(test.expected, parsed) {
+				t.Errorf(
+					"Arguments not equal.\nExpected %v\nbut got  %v",
+					test.expected,
+					parsed,
+				)
+			}
+		})
+	}
+}
+
+func TestParseArgs_Errors(t *testing.T) {
+	missing := filepath.Join(t.TempDir(), "missing.json")
+	schema := []argSpec{
+		{Name: "n", Type: argInt, Required: true},
+		{Name: "query", Type: argString, Required: true},
+	}
+
+	tests := []struct {
+		name     string
+		schema   []argSpec
+		args     []string
+		expected string
+	}{
+		{
+			name:     "missing required",
+			schema:   schema,
+			args:     []string{"3"},
+			expected: "Missing required argument 'query'",
+		},
+		{
+			name:     "missing required files",
+			schema:   []argSpec{{Name: "trees", Type: argFiles, Required: true}},
+			args:     []string{},
+			expected: "Missing required argument 'trees'",
+		},
+		{
+			name:   "not an integer",
+			schema: schema,
+			args:   []string{"three", "sr"},
+			expected: "Argument 'n': expected a positive integer but " +
+				"got 'three'",
+		},
+		{
+			name:     "non positive integer",
+			schema:   schema,
+			args:     []string{"0", "sr"},
+			expected: "Argument 'n': expected a positive integer but got '0'",
+		},
+		{
+			name:     "empty string",
+			schema:   schema,
+			args:     []string{"3", " "},
+			expected: "Argument 'query': expected a non empty string",
+		},
+		{
+			name:     "missing file",
+			schema:   []argSpec{{Name: "trees", Type: argFiles}},
+			args:     []string{missing},
+			expected: "Argument 'trees': file '" + missing + "' does not exist",
+		},
+		{
+			name:     "unknown type",
+			schema:   []argSpec{{Name: "x", Type: "float"}},
+			args:     []string{"1.5"},
+			expected: "Argument 'x': unknown type 'float'",
+		},
+		{
+			name:   "too many",
+			schema: schema,
+			args:   []string{"3", "sr", "extra"},
+			expected: "Too many arguments: expected at most 2 but " +
+				"got 3",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := parseArgs(test.schema, test.args)
+			if err == nil {
+				t.Fatalf("Expected error parsing %v", test.args)
+			}
+			if err.Error() != test.expected {
+				t.Errorf(
+					"Wrong error.\nExpected %s\nbut got  %s",
+					test.expected,
+					err.Error(),
+				)
+			}
+		})
+	}
+}
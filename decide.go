@@ -0,0 +1,347 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/jtcaraball/goexpdt/compute"
+	"github.com/jtcaraball/goexpdt/query"
+)
+
+// Decision row statuses.
+const (
+	decideSat     = "sat"
+	decideUnsat   = "unsat"
+	decideTimeout = "timeout"
+)
+
+// Result columns of the decision drivers.
+var (
+	colCNFVars    = column{"cnf_vars", "#vars", colInt, false}
+	colCNFClauses = column{"cnf_clauses", "#clauses", colInt, false}
+	colWitness    = column{"witness", "witness", colString, true}
+)
+
+var (
+	// formulaArg is the decision formula argument.
+	formulaArg = argSpec{
+		Name:     "formula",
+		Type:     argString,
+		Required: true,
+		Help:     "query language formula over the instance c and variables",
+	}
+	// randDecideArgs is the argument schema of decision drivers over random
+	// instances.
+	randDecideArgs = []argSpec{randArgs[0], formulaArg, randArgs[1]}
+	// decideArgs is the argument schema of decision drivers over given
+	// instances.
+	decideArgs = []argSpec{formulaArg, valArgs[0]}
+)
+
+// decideSchema returns the result columns of a decision driver whose rows
+// start with the columns in prefix.
+func decideSchema(prefix ...column) []column {
+	return append(
+		prefix,
+		colStatus,
+		colCNFVars,
+		colCNFClauses,
+		colTime,
+		colWitness,
+	)
+}
+
+// randDecideDriver corresponds to the driver for experiments that decide the
+// satisfiability of a formula passed as argument over random instances of the
+// target classifications.
+type randDecideDriver struct{}
+
+// Kind returns the kind of the driver.
+func (d randDecideDriver) Kind() string {
+	return specDecideRand
+}
+
+// Args returns the schema of the driver's arguments.
+func (d randDecideDriver) Args() []argSpec {
+	return randDecideArgs
+}
+
+// Schema returns the columns of the driver's results.
+func (d randDecideDriver) Schema() []column {
+	return decideSchema(
+		colSolver,
+		colFile,
+		colTreeDim,
+		colTreeNodes,
+		colTreeDepth,
+		colTreeLeaves,
+		colClass,
+		colTarget,
+		colSeed,
+		colIter,
+		colInstance,
+	)
+}
+
+// Run executes the experiment over the inputs passed in args and writes the
+// results to out.
+func (d randDecideDriver) Run(
+	out resultSink,
+	opts runOpts,
+	args expArgs,
+) error {
+	m := args.Int("n")
+
+	fgen, vs, err := compileDecision(args.String("formula"))
+	if err != nil {
+		return err
+	}
+
+	r := rand.New(rand.NewSource(opts.Seed))
+
+	for _, tp := range args.Files("tree_files") {
+		classes, err := targetClasses(tp, opts)
+		if err != nil {
+			return err
+		}
+
+		for _, class := range classes {
+			ctx, err := genContext(tp, class)
+			if err != nil {
+				return err
+			}
+
+			inst, targets, err := randTargetConsts(m, opts, ctx, r)
+			if err != nil {
+				return err
+			}
+
+			depth, leaves := treeShape(ctx, opts)
+			prefix := func(i int, ctx query.QContext) []any {
+				return []any{
+					opts.Solver.Name,
+					tp,
+					ctx.Dim(),
+					len(ctx.Nodes()),
+					depth,
+					leaves,
+					class,
+					targets[i],
+					opts.Seed,
+					i,
+					inst[i].AsString(),
+				}
+			}
+
+			err = evalDecide(
+				fgen,
+				vs,
+				tp,
+				tp,
+				class,
+				opts,
+				inst,
+				prefix,
+				out,
+			)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// decideDriver corresponds to the driver for experiments that decide the
+// satisfiability of a formula passed as argument over a specific set of
+// partial instances passed as input.
+type decideDriver struct{}
+
+// Kind returns the kind of the driver.
+func (d decideDriver) Kind() string {
+	return specDecideVal
+}
+
+// Args returns the schema of the driver's arguments.
+func (d decideDriver) Args() []argSpec {
+	return decideArgs
+}
+
+// Schema returns the columns of the driver's results.
+func (d decideDriver) Schema() []column {
+	return decideSchema(
+		colSolver,
+		colFile,
+		colTreeDim,
+		colTreeNodes,
+		colTreeDepth,
+		colTreeLeaves,
+		colClass,
+		colIter,
+		colInstance,
+	)
+}
+
+// Run executes the experiment over the inputs passed in args and writes the
+// results to out.
+func (d decideDriver) Run(
+	out resultSink,
+	opts runOpts,
+	args expArgs,
+) error {
+	fgen, vs, err := compileDecision(args.String("formula"))
+	if err != nil {
+		return err
+	}
+
+	for _, ip := range args.Files("optim_files") {
+		treeFP, _, err := scanTIFile(ip)
+		if err != nil {
+			return err
+		}
+
+		classes, err := targetClasses(treeFP, opts)
+		if err != nil {
+			return err
+		}
+
+		for _, class := range classes {
+			treeFP, inst, _, err := parseTIInput(ip, class)
+			if err != nil {
+				return err
+			}
+
+			prefix := func(i int, ctx query.QContext) []any {
+				depth, leaves := treeShape(ctx, opts)
+				return []any{
+					opts.Solver.Name,
+					ip,
+					ctx.Dim(),
+					len(ctx.Nodes()),
+					depth,
+					leaves,
+					class,
+					i,
+					inst[i].AsString(),
+				}
+			}
+
+			err = evalDecide(
+				fgen,
+				vs,
+				ip,
+				treeFP,
+				class,
+				opts,
+				inst,
+				prefix,
+				out,
+			)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// evalDecide decides the satisfiability of the formula generated by fgen, over
+// the variables vs, for every instance in inst over the tree passed by treeFP
+// with positive class class and writes the output to out. Results are
+// identified by input id and rows are prefixed with the values returned by
+// prefix.
+func evalDecide(
+	fgen func(c query.QConst) compute.Encodable,
+	vs []query.QVar,
+	id, treeFP, class string,
+	opts runOpts,
+	inst []query.QConst,
+	prefix func(i int, ctx query.QContext) []any,
+	out resultSink,
+) error {
+	return runOrdered(
+		opts.doneCount(id, class),
+		len(inst),
+		opts.Jobs,
+		func() (query.QContext, error) { return genContext(treeFP, class) },
+		func(i int, ctx query.QContext) ([]any, error) {
+			defer ctx.Reset()
+
+			dec, ts, err := decide(fgen(inst[i]), vs, ctx, opts)
+			timedOut := errors.Is(err, errTimeout)
+			if err != nil && !timedOut {
+				return nil, fmt.Errorf("Compute error: %s", err.Error())
+			}
+
+			status := decideUnsat
+			switch {
+			case timedOut:
+				status = decideTimeout
+			case dec.Sat:
+				status = decideSat
+			}
+
+			var witness any
+			if dec.Sat && len(vs) > 0 {
+				witness = witnessString(vs, dec.Witness)
+			}
+
+			return append(
+				prefix(i, ctx),
+				status,
+				dec.Size.Vars,
+				dec.Size.Clauses,
+				ts.Nanoseconds(),
+				witness,
+			), nil
+		},
+		out.Write,
+	)
+}
+
+// decide decides the satisfiability of f with the solver in opts, bounded by
+// the shorter of opts.Timeout and opts.CallTimeout, and returns the decision
+// and the time it took. The implications between the features of the model
+// in ctx are conjoined to f over the variables vs. The decision holds the
+// encoding size even if the solver timed out.
+func decide(
+	f compute.Encodable,
+	vs []query.QVar,
+	ctx query.QContext,
+	opts runOpts,
+) (decision, time.Duration, error) {
+	f = withImplications(f, vs, ctx)
+
+	tmpfp, err := os.CreateTemp("", "tmp.cnf")
+	if err != nil {
+		return decision{}, 0, err
+	}
+	tmpfp.Close()
+	defer os.Remove(tmpfp.Name())
+
+	qctx, cancel := withBudget(context.Background(), opts.Timeout)
+	defer cancel()
+	cctx, cancel := withBudget(qctx, opts.CallTimeout)
+	defer cancel()
+
+	t := time.Now()
+	dec, err := solveFormula(cctx, f, vs, ctx, opts.Solver, tmpfp.Name())
+	return dec, time.Since(t), err
+}
+
+// witnessString returns the witness values of the variables vs as space
+// separated name=value pairs.
+func witnessString(vs []query.QVar, witness []query.QConst) string {
+	pairs := make([]string, len(vs))
+	for i, v := range vs {
+		pairs[i] = string(v) + "=" + witness[i].AsString()
+	}
+	return strings.Join(pairs, " ")
+}
@@ -0,0 +1,167 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/jtcaraball/goexpdt/query"
+)
+
+// rowsSink is a result sink keeping the rows written to it.
+type rowsSink struct {
+	rows [][]any
+}
+
+func (s *rowsSink) Write(row []any) error {
+	s.rows = append(s.rows, row)
+	return nil
+}
+
+func (s *rowsSink) Close() error {
+	return nil
+}
+
+func TestEvalDecide(t *testing.T) {
+	// Instances with x and y set are the only positive ones.
+	path := filepath.Join(t.TempDir(), "tree.json")
+	tBytes := []byte(`{
+		"class_names": ["no", "yes"],
+		"feature_names": ["x", "y"],
+		"nodes": {
+			"0": {"id": 0, "type": "internal", "feature_index": 0,
+				"id_left": 1, "id_right": 2},
+			"1": {"id": 1, "type": "leaf", "class": "no"},
+			"2": {"id": 2, "type": "internal", "feature_index": 1,
+				"id_left": 3, "id_right": 4},
+			"3": {"id": 3, "type": "leaf", "class": "no"},
+			"4": {"id": 4, "type": "leaf", "class": "yes"}
+		}
+	}`)
+	if err := os.WriteFile(path, tBytes, 0o644); err != nil {
+		t.Fatalf("Failed to write tree file: %s", err.Error())
+	}
+	inst := []query.QConst{newConst(t, "11"), newConst(t, "01")}
+
+	tests := []struct {
+		name      string
+		formula   string
+		timeout   time.Duration
+		statuses  []string
+		witnesses []any
+		// encoded is true if the cnf holds variables, which it does not
+		// for formulas without variables or timed out before encoding.
+		encoded bool
+	}{
+		{
+			name:      "closed",
+			formula:   "allpos(c)",
+			statuses:  []string{decideSat, decideUnsat},
+			witnesses: []any{nil, nil},
+		},
+		{
+			name:      "witness",
+			formula:   "subsumed(y, c) & allpos(y) & full(y)",
+			statuses:  []string{decideSat, decideUnsat},
+			witnesses: []any{"y=11", nil},
+			encoded:   true,
+		},
+		{
+			name:      "timeout",
+			formula:   "subsumed(y, c) & allpos(y)",
+			timeout:   time.Nanosecond,
+			statuses:  []string{decideTimeout, decideTimeout},
+			witnesses: []any{nil, nil},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			fgen, vs, err := compileDecision(test.formula)
+			if err != nil {
+				t.Fatalf("Failed to compile decision: %s", err.Error())
+			}
+			opts := runOpts{
+				Solver:  testSolver(t),
+				Timeout: test.timeout,
+				Jobs:    1,
+			}
+			prefix := func(i int, ctx query.QContext) []any {
+				return []any{i}
+			}
+			out := &rowsSink{}
+
+			if err = evalDecide(
+				fgen,
+				vs,
+				path,
+				path,
+				"yes",
+				opts,
+				inst,
+				prefix,
+				out,
+			); err != nil {
+				t.Fatalf("Failed to decide: %s", err.Error())
+			}
+
+			if len(out.rows) != len(inst) {
+				t.Fatalf("Expected %d rows but got %v", len(inst), out.rows)
+			}
+			for i, row := range out.rows {
+				// Rows hold the prefix, status, cnf size, time and witness.
+				if row[0] != i ||
+					row[1] != test.statuses[i] ||
+					row[5] != test.witnesses[i] {
+					t.Errorf(
+						"Row %d not equal.\nExpected %d %s %v\nbut got  %v",
+						i,
+						i,
+						test.statuses[i],
+						test.witnesses[i],
+						row,
+					)
+				}
+				if vars := row[2].(int); (vars > 0) != test.encoded {
+					t.Errorf("Unexpected cnf size %d in row %d", vars, i)
+				}
+			}
+		})
+	}
+}
+
+func TestWitnessString(t *testing.T) {
+	tests := []struct {
+		name     string
+		vs       []query.QVar
+		witness  []string
+		expected string
+	}{
+		{name: "none", expected: ""},
+		{
+			name:     "single",
+			vs:       []query.QVar{"y"},
+			witness:  []string{"1_0"},
+			expected: "y=1_0",
+		},
+		{
+			name:     "many",
+			vs:       []query.QVar{"y", "z", "w"},
+			witness:  []string{"1_0", "___", "011"},
+			expected: "y=1_0 z=___ w=011",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			witness := make([]query.QConst, len(test.witness))
+			for i, w := range test.witness {
+				witness[i] = newConst(t, w)
+			}
+			if s := witnessString(test.vs, witness); s != test.expected {
+				t.Errorf("Expected %q but got %q", test.expected, s)
+			}
+		})
+	}
+}
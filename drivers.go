@@ -1,211 +1,348 @@
 package main
 
 import (
-	"encoding/csv"
-	"errors"
 	"fmt"
-	"io"
-	"strconv"
+	"math/rand"
 	"time"
 
-	"github.com/jtcaraball/goexpdt/compute"
 	"github.com/jtcaraball/goexpdt/query"
 )
 
+var (
+	// randArgs is the argument schema of drivers over random instances.
+	randArgs = []argSpec{
+		{
+			Name:     "n",
+			Type:     argInt,
+			Required: true,
+			Help:     "random instances per input",
+		},
+		{
+			Name:     "tree_files",
+			Type:     argFiles,
+			Required: true,
+			Help:     "tree files to run the experiment over",
+		},
+	}
+	// valArgs is the argument schema of drivers over given instances.
+	valArgs = []argSpec{
+		{
+			Name:     "optim_files",
+			Type:     argFiles,
+			Required: true,
+			Help:     "optimization files to run the experiment over",
+		},
+	}
+)
+
+// Result columns shared by the drivers.
+var (
+	colSolver    = column{"solver", "solver", colString, false}
+	colFile      = column{"file_name", "file_name", colString, false}
+	colTreeDim   = column{"tree_dim", "tree_dim", colInt, false}
+	colTreeNodes = column{"tree_nodes", "tree_nodes", colInt, false}
+	colClass     = column{"class", "class", colString, false}
+	colTarget    = column{"target", "target", colString, true}
+	colSeed      = column{"seed", "seed", colInt, false}
+	colIter      = column{"iter", "iter", colInt, false}
+	colInstance  = column{"instance", "instance", colString, true}
+	colStatus    = column{"status", "status", colString, false}
+	colBots      = column{"bots", "#bots", colInt, false}
+	colCalls     = column{"calls", "#calls", colInt, false}
+	colTime      = column{"time_ns", "time (ns)", colInt, false}
+	colValue     = column{"value", "value", colString, true}
+
+	// colTreeDepth and colTreeLeaves are only written with opts.TreeStats.
+	colTreeDepth  = column{"tree_depth", "tree_depth", colInt, false}
+	colTreeLeaves = column{"tree_leaves", "tree_leaves", colInt, false}
+)
+
 // randCompValDriver corresponds to the driver for experiments that use random
-// positively classified instances to compute an optimal value based on the
-// property and order generated by queryGF.
+// instances of the target classifications to compute an optimal value based
+// on the property and order generated by queryGF for each instance.
 type randCompValDriver struct {
-	queryGF closeOptimQueryGenFactory
+	queryGF openOptimQueryGenFactory
+}
+
+// Kind returns the kind of the driver.
+func (d randCompValDriver) Kind() string {
+	return specRandVal
+}
+
+// Args returns the schema of the driver's arguments.
+func (d randCompValDriver) Args() []argSpec {
+	return randArgs
+}
+
+// Schema returns the columns of the driver's results.
+func (d randCompValDriver) Schema() []column {
+	return []column{
+		colSolver,
+		colFile,
+		colTreeDim,
+		colTreeNodes,
+		colTreeDepth,
+		colTreeLeaves,
+		colClass,
+		colTarget,
+		colSeed,
+		colIter,
+		colInstance,
+		colStatus,
+		colCalls,
+		colTime,
+		colValue,
+	}
 }
 
 // Run executes the experiment over the inputs passed in args and writes the
 // results to out.
 func (d randCompValDriver) Run(
-	out io.Writer,
-	solver string,
-	args ...string,
+	out resultSink,
+	opts runOpts,
+	args expArgs,
 ) error {
-	if len(args) < 2 {
-		return errors.New("Missing arguments")
-	}
+	m := args.Int("n")
 
-	w := csv.NewWriter(out)
-
-	m, err := strconv.Atoi(args[0])
-	if err != nil {
-		return fmt.Errorf("Invalid multiplier '%s'", args[0])
-	}
+	r := rand.New(rand.NewSource(opts.Seed))
 
-	if err = w.Write(
-		[]string{
-			"file_name",
-			"tree_dim",
-			"tree_nodes",
-			"iter",
-			"time (ns)",
-			"value",
-		},
-	); err != nil {
-		return err
-	}
-
-	for _, tp := range args[1:] {
-		ctx, err := genContext(tp)
+	for _, tp := range args.Files("tree_files") {
+		classes, err := targetClasses(tp, opts)
 		if err != nil {
 			return err
 		}
 
-		if err = d.eval(tp, solver, m, ctx, w); err != nil {
-			return err
+		for _, class := range classes {
+			ctx, err := genContext(tp, class)
+			if err != nil {
+				return err
+			}
+
+			if err = d.eval(tp, class, opts, r, m, ctx, out); err != nil {
+				return err
+			}
 		}
 	}
 
 	return nil
 }
 
-// eval runs the experiment on a single input m amount of times and writes the
-// output to w.
+// eval runs the experiment on a single input with positive class class m
+// amount of times per target classification, drawing the random instances
+// from r, and writes the output to out. Instances are drawn before evaluation
+// so the output does not depend on opts.Jobs.
 func (d randCompValDriver) eval(
-	id, solver string,
+	id, class string,
+	opts runOpts,
+	r *rand.Rand,
 	m int,
 	ctx query.QContext,
-	w *csv.Writer,
+	out resultSink,
 ) error {
 	v := query.QVar("x")
-	dim := strconv.Itoa(ctx.Dim())
-	nc := strconv.Itoa(len(ctx.Nodes()))
-
-	for i := 0; i < m; i++ {
-		fg, og, err := d.queryGF(ctx)
-		if err != nil {
-			return err
-		}
-
-		t := time.Now()
-
-		out, err := compute.ComputeOptim(fg, og, v, ctx, solver)
-		if err != nil {
-			return fmt.Errorf("Compute error: %s", err.Error())
-		}
-
-		val := "-"
-		if out.Found {
-			val = out.Value.AsString()
-		}
-		ts := strconv.Itoa(int(time.Since(t)))
-
-		if err = w.Write(
-			[]string{id, dim, nc, strconv.Itoa(i), val, ts},
-		); err != nil {
-			return err
-		}
-
-		w.Flush() // Experiments are long. Save outputs often.
-		ctx.Reset()
+	dim := ctx.Dim()
+	nc := len(ctx.Nodes())
+	depth, leaves := treeShape(ctx, opts)
+
+	inst, targets, err := randQueryConsts(
+		constFree(d.queryGF, ctx),
+		m,
+		opts,
+		ctx,
+		r,
+	)
+	if err != nil {
+		return err
 	}
 
-	return nil
+	return runOrdered(
+		opts.doneCount(id, class),
+		len(inst),
+		opts.Jobs,
+		func() (query.QContext, error) { return genContext(id, class) },
+		func(i int, ctx query.QContext) ([]any, error) {
+			defer ctx.Reset()
+
+			fg, og, err := d.queryGF(ctx, inst[i])
+			if err != nil {
+				return nil, err
+			}
+
+			t := time.Now()
+
+			res, err := computeOptim(fg, og, v, ctx, opts)
+			if err != nil {
+				return nil, fmt.Errorf("Compute error: %s", err.Error())
+			}
+
+			var val any
+			if res.Found {
+				val = res.Value.AsString()
+			}
+			ts := time.Since(t).Nanoseconds()
+
+			return []any{
+				opts.Solver.Name,
+				id,
+				dim,
+				nc,
+				depth,
+				leaves,
+				class,
+				optString(targets[i]),
+				opts.Seed,
+				i,
+				optString(inst[i].AsString()),
+				res.Status(),
+				res.Calls,
+				ts,
+				val,
+			}, nil
+		},
+		out.Write,
+	)
 }
 
 // randStatsDriver corresponds to the driver for experiments that use random
-// positively classified instances to calculate stats for computing optimal
-// values based on the property and order generated by queryGF.
+// instances of the target classifications to calculate stats for computing
+// optimal values based on the property and order generated by queryGF for
+// each instance.
 type randStatsDriver struct {
-	queryGF closeOptimQueryGenFactory
+	queryGF openOptimQueryGenFactory
+}
+
+// Kind returns the kind of the driver.
+func (d randStatsDriver) Kind() string {
+	return specRandStats
+}
+
+// Args returns the schema of the driver's arguments.
+func (d randStatsDriver) Args() []argSpec {
+	return randArgs
+}
+
+// Schema returns the columns of the driver's results.
+func (d randStatsDriver) Schema() []column {
+	return []column{
+		colSolver,
+		colFile,
+		colTreeDim,
+		colTreeNodes,
+		colTreeDepth,
+		colTreeLeaves,
+		colClass,
+		colTarget,
+		colSeed,
+		colIter,
+		colInstance,
+		colStatus,
+		colBots,
+		colCalls,
+		colTime,
+	}
 }
 
 // Run executes the experiment over the inputs passed in args and writes the
 // results to out.
 func (d randStatsDriver) Run(
-	out io.Writer,
-	solver string,
-	args ...string,
+	out resultSink,
+	opts runOpts,
+	args expArgs,
 ) error {
-	if len(args) < 2 {
-		return errors.New("Missing arguments")
-	}
-
-	w := csv.NewWriter(out)
-
-	m, err := strconv.Atoi(args[0])
-	if err != nil {
-		return fmt.Errorf("Invalid multiplier '%s'", args[0])
-	}
+	m := args.Int("n")
 
-	if err = w.Write(
-		[]string{
-			"file_name",
-			"tree_dim",
-			"tree_nodes",
-			"iter",
-			"#bots",
-			"#calls",
-			"time (ns)",
-		},
-	); err != nil {
-		return err
-	}
+	r := rand.New(rand.NewSource(opts.Seed))
 
-	for _, tp := range args[1:] {
-		ctx, err := genContext(tp)
+	for _, tp := range args.Files("tree_files") {
+		classes, err := targetClasses(tp, opts)
 		if err != nil {
 			return err
 		}
 
-		if err = d.eval(tp, solver, m, ctx, w); err != nil {
-			return err
+		for _, class := range classes {
+			ctx, err := genContext(tp, class)
+			if err != nil {
+				return err
+			}
+
+			if err = d.eval(tp, class, opts, r, m, ctx, out); err != nil {
+				return err
+			}
 		}
 	}
 
 	return nil
 }
 
-// eval runs the experiment on a single input m amount of times and writes the
-// output to w.
+// eval runs the experiment on a single input with positive class class m
+// amount of times per target classification, drawing the random instances
+// from r, and writes the output to out. Instances are drawn before evaluation
+// so the output does not depend on opts.Jobs.
 func (d randStatsDriver) eval(
-	id, solver string,
+	id, class string,
+	opts runOpts,
+	r *rand.Rand,
 	m int,
 	ctx query.QContext,
-	w *csv.Writer,
+	out resultSink,
 ) error {
 	v := query.QVar("x")
-	dim := strconv.Itoa(ctx.Dim())
-	nc := strconv.Itoa(len(ctx.Nodes()))
-
-	for i := 0; i < m; i++ {
-		fg, og, err := d.queryGF(ctx)
-		if err != nil {
-			return err
-		}
-
-		t := time.Now()
-
-		out, err := compute.ComputeOptim(fg, og, v, ctx, solver)
-		if err != nil {
-			return fmt.Errorf("Compute error: %s", err.Error())
-		}
-		ts := strconv.Itoa(int(time.Since(t)))
+	dim := ctx.Dim()
+	nc := len(ctx.Nodes())
+	depth, leaves := treeShape(ctx, opts)
+
+	inst, targets, err := randQueryConsts(
+		constFree(d.queryGF, ctx),
+		m,
+		opts,
+		ctx,
+		r,
+	)
+	if err != nil {
+		return err
+	}
 
-		if err = w.Write(
-			[]string{
+	return runOrdered(
+		opts.doneCount(id, class),
+		len(inst),
+		opts.Jobs,
+		func() (query.QContext, error) { return genContext(id, class) },
+		func(i int, ctx query.QContext) ([]any, error) {
+			defer ctx.Reset()
+
+			fg, og, err := d.queryGF(ctx, inst[i])
+			if err != nil {
+				return nil, err
+			}
+
+			t := time.Now()
+
+			res, err := computeOptim(fg, og, v, ctx, opts)
+			if err != nil {
+				return nil, fmt.Errorf("Compute error: %s", err.Error())
+			}
+			ts := time.Since(t).Nanoseconds()
+
+			return []any{
+				opts.Solver.Name,
 				id,
 				dim,
 				nc,
-				strconv.Itoa(i),
-				strconv.Itoa(out.Value.BotCount()),
-				strconv.Itoa(out.Calls),
+				depth,
+				leaves,
+				class,
+				optString(targets[i]),
+				opts.Seed,
+				i,
+				optString(inst[i].AsString()),
+				res.Status(),
+				res.Value.BotCount(),
+				res.Calls,
 				ts,
-			},
-		); err != nil {
-			return err
-		}
-
-		w.Flush() // Experiments are long. Save outputs often.
-		ctx.Reset()
-	}
-
-	return nil
+			}, nil
+		},
+		out.Write,
+	)
 }
 
 // compValDriver corresponds to the driver for experiments that compute an
@@ -215,69 +352,118 @@ type compValDriver struct {
 	queryGF openOptimQueryGenFactory
 }
 
-// Run executes the experiment over the inputs passed in args and writes the
-// results to out.
-func (d compValDriver) Run(out io.Writer, solver string, args ...string) error {
-	if len(args) == 0 {
-		return errors.New("Missing arguments")
-	}
-
-	w := csv.NewWriter(out)
-
-	if err := w.Write(
-		[]string{"file_name", "tree_dim", "tree_nodes", "time (ns)", "value"},
-	); err != nil {
-		return err
-	}
-
-	for _, tp := range args {
-		if err := d.eval(tp, solver, w); err != nil {
-			return err
-		}
-	}
+// Kind returns the kind of the driver.
+func (d compValDriver) Kind() string {
+	return specVal
+}
 
-	return nil
+// Args returns the schema of the driver's arguments.
+func (d compValDriver) Args() []argSpec {
+	return valArgs
 }
 
-// eval runs the experiment on a single input  writes the outputs to w.
-func (d compValDriver) eval(ip, solver string, w *csv.Writer) error {
-	inst, ctx, err := parseTIInput(ip)
-	if err != nil {
-		return err
+// Schema returns the columns of the driver's results.
+func (d compValDriver) Schema() []column {
+	return []column{
+		colSolver,
+		colFile,
+		colTreeDim,
+		colTreeNodes,
+		colTreeDepth,
+		colTreeLeaves,
+		colClass,
+		colStatus,
+		colCalls,
+		colTime,
+		colValue,
 	}
+}
 
-	v := query.QVar("x")
-	dim := strconv.Itoa(ctx.Dim())
-	nc := strconv.Itoa(len(ctx.Nodes()))
-
-	for _, c := range inst {
-		fg, og, err := d.queryGF(ctx, c)
+// Run executes the experiment over the inputs passed in args and writes the
+// results to out.
+func (d compValDriver) Run(
+	out resultSink,
+	opts runOpts,
+	args expArgs,
+) error {
+	for _, ip := range args.Files("optim_files") {
+		treeFP, _, err := scanTIFile(ip)
 		if err != nil {
 			return err
 		}
 
-		t := time.Now()
-
-		out, err := compute.ComputeOptim(fg, og, v, ctx, solver)
+		classes, err := targetClasses(treeFP, opts)
 		if err != nil {
-			return fmt.Errorf("Compute error: %s", err.Error())
+			return err
 		}
 
-		val := "-"
-		if out.Found {
-			val = out.Value.AsString()
+		for _, class := range classes {
+			if err = d.eval(ip, class, opts, out); err != nil {
+				return err
+			}
 		}
-		ts := strconv.Itoa(int(time.Since(t)))
+	}
 
-		if err = w.Write(
-			[]string{ip, dim, nc, ts, val},
-		); err != nil {
-			return err
-		}
+	return nil
+}
 
-		w.Flush() // Experiments are long. Save outputs often.
-		ctx.Reset()
+// eval runs the experiment on a single input with positive class class and
+// writes the outputs to out.
+func (d compValDriver) eval(
+	ip, class string,
+	opts runOpts,
+	out resultSink,
+) error {
+	treeFP, inst, ctx, err := parseTIInput(ip, class)
+	if err != nil {
+		return err
 	}
 
-	return nil
+	v := query.QVar("x")
+	dim := ctx.Dim()
+	nc := len(ctx.Nodes())
+	depth, leaves := treeShape(ctx, opts)
+
+	return runOrdered(
+		opts.doneCount(ip, class),
+		len(inst),
+		opts.Jobs,
+		func() (query.QContext, error) { return genContext(treeFP, class) },
+		func(i int, ctx query.QContext) ([]any, error) {
+			defer ctx.Reset()
+
+			fg, og, err := d.queryGF(ctx, inst[i])
+			if err != nil {
+				return nil, err
+			}
+
+			t := time.Now()
+
+			res, err := computeOptim(fg, og, v, ctx, opts)
+			if err != nil {
+				return nil, fmt.Errorf("Compute error: %s", err.Error())
+			}
+
+			var val any
+			if res.Found {
+				val = res.Value.AsString()
+			}
+			ts := time.Since(t).Nanoseconds()
+
+			return []any{
+				opts.Solver.Name,
+				ip,
+				dim,
+				nc,
+				depth,
+				leaves,
+				class,
+				res.Status(),
+				res.Calls,
+				ts,
+				val,
+			}, nil
+		},
+		out.Write,
+	)
 }
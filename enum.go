@@ -0,0 +1,437 @@
+package main
+
+import (
+	"fmt"
+	"math/rand"
+	"time"
+
+	"github.com/jtcaraball/goexpdt/compute"
+	"github.com/jtcaraball/goexpdt/query"
+	"github.com/jtcaraball/goexpdt/query/logop"
+)
+
+// Enumeration row statuses. Every optimum found is written with status
+// enumFound and the enumeration of an instance is closed by a row with one of
+// the remaining statuses.
+const (
+	// enumFound marks a row holding an optimum.
+	enumFound = "found"
+	// enumDone closes an enumeration that found every optimum.
+	enumDone = "done"
+	// enumLimit closes an enumeration stopped by its limit.
+	enumLimit = "limit"
+	// enumTimeout closes an enumeration stopped by its time budget.
+	enumTimeout = "timeout"
+)
+
+// Result columns of the enumeration drivers.
+var (
+	colReason = column{"reason", "reason", colInt, false}
+)
+
+// enumStep holds the outcome of the search for a single optimum.
+type enumStep struct {
+	// Status is enumFound if Value is a new optimum and the closing status of
+	// the enumeration otherwise.
+	Status string
+	Value  query.QConst
+	// Calls and Time are the solver calls made and time elapsed since the
+	// start of the enumeration.
+	Calls int
+	Time  time.Duration
+}
+
+// enumOptims enumerates up to limit optimal instances that satisfy the
+// formula generated by fg according to the order generated by og. After an
+// optimum c is found the search is restricted to the instances satisfying
+// block(c). The returned steps hold every optimum found followed by a step
+// closing the enumeration. opts.Timeout bounds the whole enumeration.
+func enumOptims(
+	fg compute.SVFormula,
+	og compute.VCOrder,
+	block blockFormulaGen,
+	limit int,
+	v query.QVar,
+	ctx query.QContext,
+	opts runOpts,
+) ([]enumStep, error) {
+	var (
+		steps  []enumStep
+		blocks []compute.SVFormula
+		calls  int
+	)
+
+	start := time.Now()
+
+	for {
+		if len(steps) == limit {
+			return append(steps, enumStep{
+				Status: enumLimit,
+				Calls:  calls,
+				Time:   time.Since(start),
+			}), nil
+		}
+
+		sOpts := opts
+		if opts.Timeout > 0 {
+			sOpts.Timeout = opts.Timeout - time.Since(start)
+			if sOpts.Timeout <= 0 {
+				return append(steps, enumStep{
+					Status: enumTimeout,
+					Calls:  calls,
+					Time:   time.Since(start),
+				}), nil
+			}
+		}
+
+		bs := blocks
+		bfg := func(v query.QVar) compute.Encodable {
+			var f compute.Encodable = fg(v)
+			for _, b := range bs {
+				f = logop.And{Q1: f, Q2: b(v)}
+			}
+			return f
+		}
+
+		ctx.Reset()
+		out, err := computeOptim(bfg, og, v, ctx, sOpts)
+		if err != nil {
+			return nil, err
+		}
+		calls += out.Calls
+
+		step := enumStep{Calls: calls, Time: time.Since(start)}
+		switch {
+		case out.TimedOut:
+			step.Status = enumTimeout
+		case !out.Found:
+			step.Status = enumDone
+		default:
+			step.Status, step.Value = enumFound, out.Value
+			blocks = append(blocks, block(out.Value))
+		}
+		steps = append(steps, step)
+
+		if step.Status != enumFound {
+			return steps, nil
+		}
+	}
+}
+
+// enumRows returns the result rows of the enumeration steps prefixing every
+// row with the values in prefix.
+func enumRows(prefix []any, steps []enumStep) [][]any {
+	rows := make([][]any, len(steps))
+	for i, s := range steps {
+		var val any
+		if s.Status == enumFound {
+			val = s.Value.AsString()
+		}
+		reason := i + 1
+		if s.Status != enumFound {
+			reason = i
+		}
+		rows[i] = append(
+			append([]any{}, prefix...),
+			reason,
+			s.Status,
+			s.Calls,
+			s.Time.Nanoseconds(),
+			val,
+		)
+	}
+	return rows
+}
+
+// writeRows returns a function writing every row it receives to out.
+func writeRows(out resultSink) func([][]any) error {
+	return func(rows [][]any) error {
+		for _, row := range rows {
+			if err := out.Write(row); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+}
+
+var (
+	// randEnumArgs is the argument schema of enumeration drivers over random
+	// instances.
+	randEnumArgs = []argSpec{
+		randArgs[0],
+		{
+			Name:     "limit",
+			Type:     argInt,
+			Required: true,
+			Help:     "maximum number of optima enumerated per instance",
+		},
+		randArgs[1],
+	}
+	// enumArgs is the argument schema of enumeration drivers over given
+	// instances.
+	enumArgs = []argSpec{randEnumArgs[1], valArgs[0]}
+)
+
+// randEnumDriver corresponds to the driver for experiments that use random
+// instances of the target classifications to enumerate the optimal values
+// based on the property and order generated by queryGF for each instance,
+// blocking every optimum found with blockGF.
+type randEnumDriver struct {
+	queryGF openOptimQueryGenFactory
+	blockGF blockGenFactory
+}
+
+// Kind returns the kind of the driver.
+func (d randEnumDriver) Kind() string {
+	return specEnumRand
+}
+
+// Args returns the schema of the driver's arguments.
+func (d randEnumDriver) Args() []argSpec {
+	return randEnumArgs
+}
+
+// Schema returns the columns of the driver's results.
+func (d randEnumDriver) Schema() []column {
+	return []column{
+		colSolver,
+		colFile,
+		colTreeDim,
+		colTreeNodes,
+		colTreeDepth,
+		colTreeLeaves,
+		colClass,
+		colTarget,
+		colSeed,
+		colIter,
+		colInstance,
+		colReason,
+		colStatus,
+		colCalls,
+		colTime,
+		colValue,
+	}
+}
+
+// Run executes the experiment over the inputs passed in args and writes the
+// results to out.
+func (d randEnumDriver) Run(
+	out resultSink,
+	opts runOpts,
+	args expArgs,
+) error {
+	m := args.Int("n")
+	limit := args.Int("limit")
+
+	r := rand.New(rand.NewSource(opts.Seed))
+
+	for _, tp := range args.Files("tree_files") {
+		classes, err := targetClasses(tp, opts)
+		if err != nil {
+			return err
+		}
+
+		for _, class := range classes {
+			ctx, err := genContext(tp, class)
+			if err != nil {
+				return err
+			}
+
+			free := constFree(d.queryGF, ctx)
+			if _, err = d.blockGF(ctx); err != nil {
+				free = false
+			}
+			inst, targets, err := randQueryConsts(
+				free,
+				m,
+				opts,
+				ctx,
+				r,
+			)
+			if err != nil {
+				return err
+			}
+
+			depth, leaves := treeShape(ctx, opts)
+			prefix := func(i int, ctx query.QContext) []any {
+				return []any{
+					opts.Solver.Name,
+					tp,
+					ctx.Dim(),
+					len(ctx.Nodes()),
+					depth,
+					leaves,
+					class,
+					optString(targets[i]),
+					opts.Seed,
+					i,
+					optString(inst[i].AsString()),
+				}
+			}
+
+			err = evalEnum(
+				d.queryGF,
+				d.blockGF,
+				tp,
+				tp,
+				class,
+				opts,
+				inst,
+				limit,
+				prefix,
+				out,
+			)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// enumDriver corresponds to the driver for experiments that enumerate the
+// optimal values based on the property and order generated by queryGF for a
+// specific set of partial instances passed as input, blocking every optimum
+// found with blockGF.
+type enumDriver struct {
+	queryGF openOptimQueryGenFactory
+	blockGF blockGenFactory
+}
+
+// Kind returns the kind of the driver.
+func (d enumDriver) Kind() string {
+	return specEnumVal
+}
+
+// Args returns the schema of the driver's arguments.
+func (d enumDriver) Args() []argSpec {
+	return enumArgs
+}
+
+// Schema returns the columns of the driver's results.
+func (d enumDriver) Schema() []column {
+	return []column{
+		colSolver,
+		colFile,
+		colTreeDim,
+		colTreeNodes,
+		colTreeDepth,
+		colTreeLeaves,
+		colClass,
+		colIter,
+		colInstance,
+		colReason,
+		colStatus,
+		colCalls,
+		colTime,
+		colValue,
+	}
+}
+
+// Run executes the experiment over the inputs passed in args and writes the
+// results to out.
+func (d enumDriver) Run(
+	out resultSink,
+	opts runOpts,
+	args expArgs,
+) error {
+	limit := args.Int("limit")
+
+	for _, ip := range args.Files("optim_files") {
+		treeFP, _, err := scanTIFile(ip)
+		if err != nil {
+			return err
+		}
+
+		classes, err := targetClasses(treeFP, opts)
+		if err != nil {
+			return err
+		}
+
+		for _, class := range classes {
+			treeFP, inst, _, err := parseTIInput(ip, class)
+			if err != nil {
+				return err
+			}
+
+			prefix := func(i int, ctx query.QContext) []any {
+				depth, leaves := treeShape(ctx, opts)
+				return []any{
+					opts.Solver.Name,
+					ip,
+					ctx.Dim(),
+					len(ctx.Nodes()),
+					depth,
+					leaves,
+					class,
+					i,
+					inst[i].AsString(),
+				}
+			}
+
+			err = evalEnum(
+				d.queryGF,
+				d.blockGF,
+				ip,
+				treeFP,
+				class,
+				opts,
+				inst,
+				limit,
+				prefix,
+				out,
+			)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// evalEnum enumerates the optima of every instance in inst, generated by
+// queryGF and blocked by blockGF, over the tree passed by treeFP with positive
+// class class and writes the output to out. Results are identified by input
+// id and rows are prefixed with the values returned by prefix.
+func evalEnum(
+	queryGF openOptimQueryGenFactory,
+	blockGF blockGenFactory,
+	id, treeFP, class string,
+	opts runOpts,
+	inst []query.QConst,
+	limit int,
+	prefix func(i int, ctx query.QContext) []any,
+	out resultSink,
+) error {
+	v := query.QVar("x")
+
+	return runOrdered(
+		opts.doneCount(id, class),
+		len(inst),
+		opts.Jobs,
+		func() (query.QContext, error) { return genContext(treeFP, class) },
+		func(i int, ctx query.QContext) ([][]any, error) {
+			defer ctx.Reset()
+
+			fg, og, err := queryGF(ctx, inst[i])
+			if err != nil {
+				return nil, err
+			}
+			block, err := blockGF(ctx, inst[i])
+			if err != nil {
+				return nil, err
+			}
+
+			steps, err := enumOptims(fg, og, block, limit, v, ctx, opts)
+			if err != nil {
+				return nil, fmt.Errorf("Compute error: %s", err.Error())
+			}
+
+			return enumRows(prefix(i, ctx), steps), nil
+		},
+		writeRows(out),
+	)
+}
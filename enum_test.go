@@ -0,0 +1,213 @@
+package main
+
+import (
+	"reflect"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/jtcaraball/goexpdt/query"
+	"github.com/jtcaraball/goexpdt/query/logop"
+)
+
+func TestEnumOptims(t *testing.T) {
+	// The sufficient reasons of x > 6 are 111, 1_1 and _11 if the features
+	// are taken as binary, and only 111 under the implications. Blocking the
+	// instances extending an optimum enumerates the subset-minimal ones.
+	c := newConst(t, "111")
+	tests := []struct {
+		name    string
+		ctx     query.QContext
+		limit   int
+		timeout time.Duration
+		found   int
+		optima  []string
+		status  string
+	}{
+		{
+			name:   "binary",
+			ctx:    query.BasicQContext(thresholdTree),
+			limit:  5,
+			found:  2,
+			optima: []string{"1_1", "_11"},
+			status: enumDone,
+		},
+		{
+			name:   "implications",
+			ctx:    treeQContext(thresholdTree),
+			limit:  5,
+			found:  1,
+			optima: []string{"111"},
+			status: enumDone,
+		},
+		{
+			name:   "limit",
+			ctx:    query.BasicQContext(thresholdTree),
+			limit:  1,
+			found:  1,
+			optima: []string{"1_1", "_11"},
+			status: enumLimit,
+		},
+		{
+			name:    "timeout",
+			ctx:     query.BasicQContext(thresholdTree),
+			limit:   5,
+			timeout: time.Nanosecond,
+			status:  enumTimeout,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			opts := runOpts{Solver: testSolver(t), Timeout: test.timeout}
+			steps, err := enumOptims(
+				srFGF(c),
+				ssOGF(),
+				nsFGF,
+				test.limit,
+				query.QVar("x"),
+				test.ctx,
+				opts,
+			)
+			if err != nil {
+				t.Fatalf("Failed to enumerate optima: %s", err.Error())
+			}
+
+			var optima []string
+			for i, s := range steps[:len(steps)-1] {
+				if s.Status != enumFound {
+					t.Fatalf("Expected step %d found but got %s", i, s.Status)
+				}
+				if i > 0 && s.Calls <= steps[i-1].Calls {
+					t.Errorf("Expected calls of step %d to accumulate", i)
+				}
+				optima = append(optima, s.Value.AsString())
+			}
+			last := steps[len(steps)-1]
+			if last.Status != test.status {
+				t.Errorf(
+					"Expected closing status %s but got %s",
+					test.status,
+					last.Status,
+				)
+			}
+			if last.Value.Val != nil {
+				t.Errorf("Expected no value in closing step")
+			}
+
+			if len(optima) != test.found {
+				t.Fatalf("Expected %d optima but got %v", test.found, optima)
+			}
+			for i, o := range optima {
+				if !slices.Contains(test.optima, o) ||
+					slices.Contains(optima[:i], o) {
+					t.Errorf(
+						"Expected distinct optima among %v but got %v",
+						test.optima,
+						optima,
+					)
+					break
+				}
+			}
+		})
+	}
+}
+
+func TestEnumRows(t *testing.T) {
+	prefix := []any{"t1.json", 3}
+	steps := []enumStep{
+		{Status: enumFound, Value: newConst(t, "1_1"), Calls: 2, Time: 10},
+		{Status: enumFound, Value: newConst(t, "_11"), Calls: 5, Time: 20},
+		{Status: enumLimit, Calls: 5, Time: 25},
+	}
+
+	expected := [][]any{
+		{"t1.json", 3, 1, enumFound, 2, int64(10), "1_1"},
+		{"t1.json", 3, 2, enumFound, 5, int64(20), "_11"},
+		{"t1.json", 3, 2, enumLimit, 5, int64(25), nil},
+	}
+	rows := enumRows(prefix, steps)
+	if !reflect.DeepEqual(expected, rows) {
+		t.Errorf("Rows not equal.\nExpected %v\nbut got  %v", expected, rows)
+	}
+
+	// An enumeration finding no optimum writes only its closing row.
+	expected = [][]any{{"t1.json", 3, 0, enumDone, 1, int64(5), nil}}
+	rows = enumRows(prefix, []enumStep{{Status: enumDone, Calls: 1, Time: 5}})
+	if !reflect.DeepEqual(expected, rows) {
+		t.Errorf("Rows not equal.\nExpected %v\nbut got  %v", expected, rows)
+	}
+}
+
+func TestOptimaBlock(t *testing.T) {
+	// The block of an optimum under Less Level excludes it and the instances
+	// with fewer bottoms, which it precedes in the reverse order.
+	ctx := query.BasicQContext(thresholdTree)
+	block := optimaBlock(llROGF())
+	v := query.QVar("x")
+
+	for _, c := range allConsts(thresholdTree.Dim()) {
+		for _, x := range allConsts(thresholdTree.Dim()) {
+			expected := x.AsString() != c.AsString() &&
+				x.BotCount() >= c.BotCount()
+			if sat := satisfiableWith(
+				t,
+				logop.WithVar{I: v, Q: block(c)(v)},
+				ctx,
+				v,
+				x,
+			); sat != expected {
+				t.Errorf(
+					"Expected block of %s to allow %s %t",
+					c.AsString(),
+					x.AsString(),
+					expected,
+				)
+			}
+		}
+	}
+}
+
+func TestEnumOptims_OptimaBlock(t *testing.T) {
+	// The sufficient reasons of x > 6 with the most bottoms, taking the
+	// features as binary, are the incomparable 1_1 and _11.
+	steps, err := enumOptims(
+		srFGF(newConst(t, "111")),
+		llOGF(),
+		optimaBlock(llROGF()),
+		5,
+		query.QVar("x"),
+		query.BasicQContext(thresholdTree),
+		runOpts{Solver: testSolver(t)},
+	)
+	if err != nil {
+		t.Fatalf("Failed to enumerate optima: %s", err.Error())
+	}
+
+	var (
+		optima   []string
+		statuses []string
+	)
+	for _, s := range steps {
+		statuses = append(statuses, s.Status)
+		if s.Status == enumFound {
+			optima = append(optima, s.Value.AsString())
+		}
+	}
+	slices.Sort(optima)
+	if expected := []string{"1_1", "_11"}; !slices.Equal(expected, optima) {
+		t.Errorf(
+			"Optima not equal.\nExpected %v\nbut got  %v",
+			expected,
+			optima,
+		)
+	}
+	expected := []string{enumFound, enumFound, enumDone}
+	if !slices.Equal(expected, statuses) {
+		t.Errorf(
+			"Statuses not equal.\nExpected %v\nbut got  %v",
+			expected,
+			statuses,
+		)
+	}
+}
@@ -1,7 +1,7 @@
 package main
 
 import (
-	"io"
+	"errors"
 	"os"
 	"path"
 	"time"
@@ -9,7 +9,13 @@ import (
 
 // driver for running the optimization algorithm over a set of inputs.
 type driver interface {
-	Run(out io.Writer, solver string, args ...string) error
+	// Kind returns the driver kind, such as specRandStats or specEnumVal.
+	Kind() string
+	// Args returns the schema of the arguments taken by Run.
+	Args() []argSpec
+	// Schema returns the columns of the results written by Run.
+	Schema() []column
+	Run(out resultSink, opts runOpts, args expArgs) error
 }
 
 // experiment corresponds to a particular instance of a query, determined by
@@ -21,114 +27,251 @@ type experiment struct {
 	d           driver
 }
 
-// Run the experiment over the set of inputs and using the options contained in
-// args.
-func (e experiment) Run(args ...string) error {
-	ts := dateTimeAsString(time.Now())
-	outp := path.Join(outputdir, e.Name+"_"+ts+".csv")
+// Run the experiment over the set of inputs contained in args, validated
+// against the driver's argument schema, using the run options opts. Results
+// are written in the opts.Format format. If opts.Resume is set the results
+// are appended to that output, skipping those already in it. On error the
+// output is only removed if it holds no results so that it can be resumed.
+// The provenance of the run is recorded in a manifest next to the output.
+func (e experiment) Run(opts runOpts, args ...string) (err error) {
+	var of *os.File
 
-	of, err := os.Create(outp)
+	parsed, err := parseArgs(e.d.Args(), args)
+	if err != nil {
+		return err
+	}
+
+	if opts.Resume != "" {
+		if opts.Format != formatCSV {
+			return errors.New("Resume is only supported for csv outputs")
+		}
+		if opts.resumed, err = loadResumeState(opts.Resume, &opts); err != nil {
+			return err
+		}
+		of, err = os.OpenFile(opts.Resume, os.O_APPEND|os.O_WRONLY, 0)
+	} else {
+		ts := dateTimeAsString(time.Now())
+		of, err = os.Create(
+			path.Join(outputdir, e.Name+"_"+ts+"."+opts.Format),
+		)
+	}
 	if err != nil {
 		return err
 	}
 	defer of.Close()
 
-	if err = e.d.Run(of, solver, args...); err != nil {
-		os.Remove(of.Name())
+	out := &countingSink{}
+	manifest, err := newManifest(e, opts, parsed, of.Name())
+	if err != nil {
+		if opts.Resume == "" {
+			os.Remove(of.Name())
+		}
+		return err
+	}
+	mPath := manifestPath(of.Name(), opts.resumed != nil, manifest.Start)
+	defer func() {
+		if err != nil && opts.Resume == "" && out.rows == 0 {
+			os.Remove(of.Name())
+			os.Remove(mPath)
+			return
+		}
+		manifest.Finish(err)
+		if mErr := manifest.Write(mPath); err == nil {
+			err = mErr
+		}
+	}()
+	if err = manifest.Write(mPath); err != nil {
+		return err
+	}
+
+	schema := e.d.Schema()
+	var keep []int
+	if !opts.TreeStats {
+		schema, keep = projectSchema(
+			schema,
+			[]column{colTreeDepth, colTreeLeaves},
+		)
+	}
+	if out.resultSink, err = newSink(of, opts, schema); err != nil {
 		return err
 	}
+	if keep != nil {
+		out.resultSink = projectSink{out.resultSink, keep}
+	}
+
+	err = e.d.Run(out, opts, parsed)
+	if cErr := out.Close(); err == nil {
+		err = cErr
+	}
 
-	return nil
+	return err
 }
 
 var experiments = []experiment{
 	{
 		"optim:rand:stats:dfs-ll",
-		"Optimum (Stats, Random Instances) - DFS under Lesser Level Order.\n" +
-			"Arguments:\n" +
-			"  - n (instances per input\n" +
-			"  - List of <tree_file_inputs>",
-		randStatsDriver{DFS_LL_C},
+		"Optimum (Stats, Random Instances) - DFS under Lesser Level Order.",
+		randStatsDriver{DFS_LL_O},
 	},
 	{
 		"optim:rand:stats:sr-ll",
-		"Optimum (Stats, Random Instances) - SR under Lesser Level Order.\n" +
-			"Arguments:\n" +
-			"  - n (instances per input\n" +
-			"  - List of <tree_file_inputs>",
-		randStatsDriver{SR_LL_C},
+		"Optimum (Stats, Random Instances) - SR under Lesser Level Order.",
+		randStatsDriver{SR_LL_O},
 	},
 	{
 		"optim:rand:stats:sr-ss",
 		"Optimum (Stats, Random Instances) - SR under Strict Subsumption" +
-			" Order.\n" +
-			"Arguments:\n" +
-			"  - n (instances per input\n" +
-			"  - List of <tree_file_inputs>",
-		randStatsDriver{SR_SS_C},
+			" Order.",
+		randStatsDriver{SR_SS_O},
 	},
 	{
 		"optim:rand:stats:cr-lh",
 		"Optimum (Stats, Random Instances) - CR under Lesser Hamming" +
-			" Distance Order.\n" +
-			"Arguments:\n" +
-			"  - n (instances per input\n" +
-			"  - List of <tree_file_inputs>",
-		randStatsDriver{CR_LH_C},
+			" Distance Order.",
+		randStatsDriver{CR_LH_O},
 	},
 	{
 		"optim:rand:stats:ca-gh",
 		"Optimum (Stats, Random Instances) - CA under Greater Hamming" +
-			" Distance Order.\n" +
-			"Arguments:\n" +
-			"  - n (instances per input\n" +
-			"  - List of <tree_file_inputs>",
-		randStatsDriver{CA_GH_C},
+			" Distance Order.",
+		randStatsDriver{CA_GH_O},
+	},
+	{
+		"optim:rand:stats:ct-gl",
+		"Optimum (Stats, Random Instances) - CT under Greater Level Order.",
+		randStatsDriver{CT_GL_O},
 	},
 	{
 		"optim:rand:val:dfs-ll",
-		"Optimum (Value, Random Instances) - DFS under Lesser Level Order.\n" +
-			"Arguments:\n" +
-			"  - n (instances per input\n" +
-			"  - List of <tree_file_inputs>",
-		randCompValDriver{DFS_LL_C},
+		"Optimum (Value, Random Instances) - DFS under Lesser Level Order.",
+		randCompValDriver{DFS_LL_O},
+	},
+	{
+		"optim:rand:val:sr-ll",
+		"Optimum (Value, Random Instances) - SR under Lesser Level Order.",
+		randCompValDriver{SR_LL_O},
+	},
+	{
+		"optim:rand:val:ct-gl",
+		"Optimum (Value, Random Instances) - CT under Greater Level Order.",
+		randCompValDriver{CT_GL_O},
 	},
 	{
 		"optim:val:dfs-ll",
-		"Optimum (Value) - DFS under Lesser Level Order.\n" +
-			"Arguments:\n" +
-			"  - n (instances per input\n" +
-			"  - List of <tree_file_inputs>",
+		"Optimum (Value) - DFS under Lesser Level Order.",
 		compValDriver{DFS_LL_O},
 	},
 	{
 		"optim:val:sr-ll",
-		"Optimum (Value) - SR under Lesser Level Order.\n" +
-			"Arguments:\n" +
-			"  - List of <optim_file_input>",
+		"Optimum (Value) - SR under Lesser Level Order.",
 		compValDriver{SR_LL_O},
 	},
 	{
 		"optim:val:sr-ss",
-		"Optimum (Value) - SR under Strict Subsumption Order.\n" +
-			"Arguments:\n" +
-			"  - List of <optim_file_input>",
+		"Optimum (Value) - SR under Strict Subsumption Order.",
 		compValDriver{SR_SS_O},
 	},
 	{
 		"optim:val:cr-lh",
-		"Optimum (Value) - CR under Less Hamming Distance Order.\n" +
-			"Arguments:\n" +
-			"  - List of <optim_file_input>",
+		"Optimum (Value) - CR under Less Hamming Distance Order.",
 		compValDriver{CR_LH_O},
 	},
 	{
 		"optim:val:ca-gh",
-		"Optimum (Value) - CA under Greater Hamming Distance Order.\n" +
-			"Arguments:\n" +
-			"  - List of <optim_file_input>",
+		"Optimum (Value) - CA under Greater Hamming Distance Order.",
 		compValDriver{CA_GH_O},
 	},
+	{
+		"optim:val:ct-gl",
+		"Optimum (Value) - CT under Greater Level Order.",
+		compValDriver{CT_GL_O},
+	},
+	{
+		"enum:rand:sr-ss",
+		"Enumeration (Random Instances) - SR under Strict Subsumption" +
+			" Order.",
+		randEnumDriver{SR_SS_O, NS_B},
+	},
+	{
+		"enum:val:sr-ss",
+		"Enumeration - SR under Strict Subsumption Order.",
+		enumDriver{SR_SS_O, NS_B},
+	},
+	{
+		"enum:rand:dfs-ll",
+		"Enumeration (Random Instances) - DFS under Lesser Level Order.",
+		randEnumDriver{DFS_LL_O, LL_B},
+	},
+	{
+		"enum:rand:sr-ll",
+		"Enumeration (Random Instances) - SR under Lesser Level Order.",
+		randEnumDriver{SR_LL_O, LL_B},
+	},
+	{
+		"enum:rand:cr-lh",
+		"Enumeration (Random Instances) - CR under Lesser Hamming" +
+			" Distance Order.",
+		randEnumDriver{CR_LH_O, LH_B},
+	},
+	{
+		"enum:rand:ca-gh",
+		"Enumeration (Random Instances) - CA under Greater Hamming" +
+			" Distance Order.",
+		randEnumDriver{CA_GH_O, GH_B},
+	},
+	{
+		"enum:rand:ct-gl",
+		"Enumeration (Random Instances) - CT under Greater Level Order.",
+		randEnumDriver{CT_GL_O, GL_B},
+	},
+	{
+		"enum:val:dfs-ll",
+		"Enumeration - DFS under Lesser Level Order.",
+		enumDriver{DFS_LL_O, LL_B},
+	},
+	{
+		"enum:val:sr-ll",
+		"Enumeration - SR under Lesser Level Order.",
+		enumDriver{SR_LL_O, LL_B},
+	},
+	{
+		"enum:val:cr-lh",
+		"Enumeration - CR under Lesser Hamming Distance Order.",
+		enumDriver{CR_LH_O, LH_B},
+	},
+	{
+		"enum:val:ca-gh",
+		"Enumeration - CA under Greater Hamming Distance Order.",
+		enumDriver{CA_GH_O, GH_B},
+	},
+	{
+		"enum:val:ct-gl",
+		"Enumeration - CT under Greater Level Order.",
+		enumDriver{CT_GL_O, GL_B},
+	},
+	{
+		"decide:rand",
+		"Decision (Random Instances) - satisfiability of a query language" +
+			" formula.",
+		randDecideDriver{},
+	},
+	{
+		"decide:val",
+		"Decision - satisfiability of a query language formula.",
+		decideDriver{},
+	},
+}
+
+// expInfo is the machine readable description of an experiment.
+type expInfo struct {
+	Name        string    `json:"name"`
+	Description string    `json:"description"`
+	Args        []argSpec `json:"args"`
+}
+
+// Info returns the description of the experiment and its arguments.
+func (e experiment) Info() expInfo {
+	return expInfo{Name: e.Name, Description: e.Description, Args: e.d.Args()}
 }
 
 // expMap returns map of implemented experiments with their name as key.
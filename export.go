@@ -0,0 +1,49 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"goexpdt-experiments/tree"
+
+	"github.com/jtcaraball/goexpdt/query"
+)
+
+// Tree export formats.
+const (
+	exportDOT     = "dot"
+	exportMermaid = "mermaid"
+)
+
+// runExport writes to out the tree encoded in the file passed by treeFP in
+// the given format. If inst is not empty the nodes consistent with the
+// instance it represents are highlighted.
+func runExport(out io.Writer, treeFP, format, inst string) error {
+	t, err := tree.Load(treeFP)
+	if err != nil {
+		return err
+	}
+
+	var c *query.QConst
+	if inst != "" {
+		ic, err := parseInstance(inst, &t)
+		if err != nil {
+			return fmt.Errorf(
+				"Invalid instance '%s': %s",
+				inst,
+				strings.TrimSuffix(err.Error(), "."),
+			)
+		}
+		c = &ic
+	}
+
+	switch format {
+	case exportDOT:
+		return t.WriteDOT(out, c)
+	case exportMermaid:
+		return t.WriteMermaid(out, c)
+	default:
+		return fmt.Errorf("Unknown export format '%s'", format)
+	}
+}
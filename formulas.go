@@ -4,7 +4,6 @@ import (
 	"github.com/jtcaraball/goexpdt/compute"
 	"github.com/jtcaraball/goexpdt/query"
 	"github.com/jtcaraball/goexpdt/query/extensions/allcomp"
-	"github.com/jtcaraball/goexpdt/query/extensions/dfs"
 	"github.com/jtcaraball/goexpdt/query/extensions/full"
 	"github.com/jtcaraball/goexpdt/query/logop"
 	"github.com/jtcaraball/goexpdt/query/predicates/subsumption"
@@ -13,7 +12,7 @@ import (
 // dfsFGF returns a query generator for the formula Determinant Feature Set.
 func dfsFGF() compute.SVFormula {
 	return func(v query.QVar) compute.Encodable {
-		return dfs.Var{I: v}
+		return impliedDFS{I: v}
 	}
 }
 
@@ -127,3 +126,58 @@ func caFGF(c query.QConst) compute.SVFormula {
 		}
 	}
 }
+
+// ctFGF returns a query generator for the formula Contrastive of constant c,
+// satisfied by the partial instances subsumed by c with a completion whose
+// classification differs from that of c. Freeing the features c defines and
+// the instance does not allows the classification to flip.
+func ctFGF(c query.QConst) compute.SVFormula {
+	return func(v query.QVar) compute.Encodable {
+		return logop.WithVar{
+			I: v,
+			Q: logop.And{
+				Q1: subsumption.VarConst{I1: v, I2: c},
+				Q2: logop.Or{
+					Q1: logop.And{
+						Q1: allcomp.Const{I: c, LeafValue: true},
+						Q2: logop.Not{
+							Q: allcomp.Var{
+								I:               v,
+								LeafValue:       true,
+								ReachNodeVarGen: varGenNodeReach,
+							},
+						},
+					},
+					Q2: logop.And{
+						Q1: allcomp.Const{I: c, LeafValue: false},
+						Q2: logop.Not{
+							Q: allcomp.Var{
+								I:               v,
+								LeafValue:       false,
+								ReachNodeVarGen: varGenNodeReach,
+							},
+						},
+					},
+				},
+			},
+		}
+	}
+}
+
+// nsFGF returns a query generator for the formula Not Subsumed of constant c,
+// satisfied by the instances that do not extend c.
+func nsFGF(c query.QConst) compute.SVFormula {
+	return func(v query.QVar) compute.Encodable {
+		return logop.Not{Q: subsumption.ConstVar{I1: c, I2: v}}
+	}
+}
+
+// eqFGF returns a query generator for the formula Equal to constant c.
+func eqFGF(c query.QConst) compute.SVFormula {
+	return func(v query.QVar) compute.Encodable {
+		return logop.And{
+			Q1: subsumption.VarConst{I1: v, I2: c},
+			Q2: subsumption.ConstVar{I1: c, I2: v},
+		}
+	}
+}
@@ -0,0 +1,320 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/jtcaraball/goexpdt/cnf"
+	"github.com/jtcaraball/goexpdt/compute"
+	"github.com/jtcaraball/goexpdt/query"
+	"github.com/jtcaraball/goexpdt/query/extensions/dfs"
+	"github.com/jtcaraball/goexpdt/query/logop"
+)
+
+// Partial instances of a tree binarized from the thresholds of its real
+// features are kept closed under the implications between its features: a
+// feature is defined whenever a feature implying it is ONE or a feature
+// implied by it is ZERO. Every set of original instances is then described
+// by a single partial instance, so that predicates and orders compare the
+// instances they describe. Instances given as input are closed when parsed
+// and query variables are restricted to closed values. Trees are assumed to
+// have no branch made unreachable by the thresholds of the splits above it,
+// as the AllComp extension follows both branches of an undefined feature.
+
+// impliedModel is a model whose features are ordered by implications, as the
+// features of a tree binarized from the thresholds of real features are.
+type impliedModel interface {
+	query.Model
+	// Implications returns pairs (i, j) meaning that feature i being ONE
+	// implies feature j being ONE.
+	Implications() [][2]int
+}
+
+// treeContext is a query context holding its model and the implications
+// between the model's features.
+type treeContext struct {
+	query.QContext
+	model        impliedModel
+	implications [][2]int
+}
+
+// treeQContext returns a basic query context over m holding its
+// implications.
+func treeQContext(m impliedModel) query.QContext {
+	return &treeContext{
+		QContext:     query.BasicQContext(m),
+		model:        m,
+		implications: m.Implications(),
+	}
+}
+
+// implications returns the implications between the features of the model in
+// ctx or nil if ctx holds none.
+func implications(ctx query.QContext) [][2]int {
+	if tc, ok := ctx.(*treeContext); ok {
+		return tc.implications
+	}
+	return nil
+}
+
+// impliedPairs returns the transitive closure of the implications imps.
+// Partial instances may leave features between two others undefined, so
+// every implied pair must be checked and not only those given.
+func impliedPairs(imps [][2]int) [][2]int {
+	next := make(map[int][]int)
+	for _, imp := range imps {
+		next[imp[0]] = append(next[imp[0]], imp[1])
+	}
+
+	var pairs [][2]int
+	for from := range next {
+		seen := map[int]bool{from: true}
+		toVisit := append([]int{}, next[from]...)
+		for len(toVisit) > 0 {
+			to := toVisit[len(toVisit)-1]
+			toVisit = toVisit[:len(toVisit)-1]
+			if seen[to] {
+				continue
+			}
+			seen[to] = true
+			pairs = append(pairs, [2]int{from, to})
+			toVisit = append(toVisit, next[to]...)
+		}
+	}
+	return pairs
+}
+
+// closedConst returns the constant c with every feature implied by its values
+// defined, that is with ONE on the features implied by a feature with value
+// ONE and ZERO on those implying a feature with value ZERO. Returns false if
+// the implications contradict the values of c, that is if no instance of the
+// original feature space completes it.
+func closedConst(c query.QConst, imps [][2]int) (query.QConst, bool) {
+	closed := query.QConst{Val: append([]query.FeatV{}, c.Val...)}
+	for _, p := range impliedPairs(imps) {
+		if c.Val[p[0]] == query.ONE {
+			if closed.Val[p[1]] == query.ZERO {
+				return closed, false
+			}
+			closed.Val[p[1]] = query.ONE
+		}
+		if c.Val[p[1]] == query.ZERO {
+			if closed.Val[p[0]] == query.ONE {
+				return closed, false
+			}
+			closed.Val[p[0]] = query.ZERO
+		}
+	}
+	return closed, true
+}
+
+// impliedVar restricts the variable I to the partial instances closed under
+// the implications between the features of the model. As the clauses of a
+// WithVar restricting a variable to a single value per feature, they are
+// encoded as consistency clauses and so hold even if negated.
+type impliedVar struct {
+	I            query.QVar
+	Implications [][2]int
+}
+
+// Encoding returns the consistency clauses of the implications over the
+// variable d.I.
+func (d impliedVar) Encoding(ctx query.QContext) (cnf.CNF, error) {
+	sv := ctx.ScopeVar(d.I)
+	clauses := []cnf.Clause{}
+	for _, p := range impliedPairs(d.Implications) {
+		clauses = append(
+			clauses,
+			cnf.Clause{
+				-ctx.CNFVar(sv, p[0], int(query.ONE)),
+				ctx.CNFVar(sv, p[1], int(query.ONE)),
+			},
+			cnf.Clause{
+				-ctx.CNFVar(sv, p[1], int(query.ZERO)),
+				ctx.CNFVar(sv, p[0], int(query.ZERO)),
+			},
+		)
+	}
+	return cnf.CNF{}.AppendConsistency(clauses...), nil
+}
+
+// withImplications returns the formula f conjoined with the implications
+// between the features of the model in ctx over every variable in vs. Returns
+// f unchanged if the model has no implications.
+func withImplications(
+	f compute.Encodable,
+	vs []query.QVar,
+	ctx query.QContext,
+) compute.Encodable {
+	imps := implications(ctx)
+	if len(imps) == 0 {
+		return f
+	}
+	for _, v := range vs {
+		f = logop.And{Q1: impliedVar{I: v, Implications: imps}, Q2: f}
+	}
+	return f
+}
+
+// impliedQuery returns the formula and order generators fg and og with the
+// implications between the features of the model in ctx conjoined to every
+// formula and order they generate.
+func impliedQuery(
+	fg compute.SVFormula,
+	og compute.VCOrder,
+	ctx query.QContext,
+) (compute.SVFormula, compute.VCOrder) {
+	if len(implications(ctx)) == 0 {
+		return fg, og
+	}
+	ifg := func(v query.QVar) compute.Encodable {
+		return withImplications(fg(v), []query.QVar{v}, ctx)
+	}
+	iog := func(v query.QVar, c query.QConst) compute.Encodable {
+		return withImplications(og(v, c), []query.QVar{v}, ctx)
+	}
+	return ifg, iog
+}
+
+// impliedDFS is the DFS extension over a model with implications between its
+// features. The leaves of the model are closed under the implications before
+// comparing them, and leaves whose path contradicts the implications are
+// ignored. The variable I is expected to be closed.
+type impliedDFS struct {
+	I query.QVar
+}
+
+// Encoding returns a CNF that is true if and only if every completion of the
+// variable d.I allowed by the implications has the same model evaluation.
+func (d impliedDFS) Encoding(ctx query.QContext) (cnf.CNF, error) {
+	imps := implications(ctx)
+	if len(imps) == 0 {
+		return dfs.Var{I: d.I}.Encoding(ctx)
+	}
+
+	pleaf, nleaf, err := impliedLeafs(ctx, imps)
+	if err != nil {
+		return cnf.CNF{}, err
+	}
+
+	sv := ctx.ScopeVar(d.I)
+	clauses := []cnf.Clause{}
+	for _, p := range pleaf {
+		for _, n := range nleaf {
+			clause := cnf.Clause{}
+			for i := range p.Val {
+				if leafsDiffer(p, n, i) {
+					clause = append(clause, -ctx.CNFVar(sv, i, int(query.BOT)))
+				}
+			}
+			clauses = append(clauses, clause)
+		}
+	}
+
+	return cnf.FromClauses(clauses), nil
+}
+
+// impliedDFSConst is the constant version of impliedDFS. The constant I is
+// closed before comparing it with the leaves.
+type impliedDFSConst struct {
+	I query.QConst
+}
+
+// Encoding returns a CNF that is true if and only if every completion of the
+// constant d.I allowed by the implications has the same model evaluation.
+func (d impliedDFSConst) Encoding(ctx query.QContext) (cnf.CNF, error) {
+	imps := implications(ctx)
+	if len(imps) == 0 {
+		return dfs.Const{I: d.I}.Encoding(ctx)
+	}
+
+	c, ok := closedConst(d.I, imps)
+	if !ok {
+		return cnf.TrueCNF, nil
+	}
+
+	pleaf, nleaf, err := impliedLeafs(ctx, imps)
+	if err != nil {
+		return cnf.CNF{}, err
+	}
+
+	for _, p := range pleaf {
+		for _, n := range nleaf {
+			differ := false
+			for i, ft := range c.Val {
+				if ft != query.BOT && leafsDiffer(p, n, i) {
+					differ = true
+					break
+				}
+			}
+			if !differ {
+				return cnf.FalseCNF, nil
+			}
+		}
+	}
+
+	return cnf.TrueCNF, nil
+}
+
+// impliedLeafs returns the paths to the positive and negative leaves of the
+// model in ctx, in that order, as constants closed under the implications
+// imps. Leaves whose path contradicts the implications are left out.
+func impliedLeafs(
+	ctx query.QContext,
+	imps [][2]int,
+) ([]query.QConst, []query.QConst, error) {
+	nodes := ctx.Nodes()
+	if len(nodes) == 0 {
+		return nil, nil, errors.New("Invalid encoding on empty model")
+	}
+
+	var pleaf, nleaf []query.QConst
+	path := query.AllBotConst(ctx.Dim())
+
+	var walk func(id int) error
+	walk = func(id int) error {
+		if id < 0 || id >= len(nodes) {
+			return fmt.Errorf("Node's child out of bounds %d", id)
+		}
+		n := nodes[id]
+		if n.IsLeaf() {
+			c, ok := closedConst(path, imps)
+			switch {
+			case !ok:
+			case n.Value:
+				pleaf = append(pleaf, c)
+			default:
+				nleaf = append(nleaf, c)
+			}
+			return nil
+		}
+		if n.Feat < 0 || n.Feat >= ctx.Dim() {
+			return fmt.Errorf(
+				"Node's feature %d is out of range [0, %d]",
+				n.Feat,
+				ctx.Dim()-1,
+			)
+		}
+		prev := path.Val[n.Feat]
+		defer func() { path.Val[n.Feat] = prev }()
+		path.Val[n.Feat] = query.ZERO
+		if err := walk(n.ZChild); err != nil {
+			return err
+		}
+		path.Val[n.Feat] = query.ONE
+		return walk(n.OChild)
+	}
+
+	if err := walk(0); err != nil {
+		return nil, nil, err
+	}
+	return pleaf, nleaf, nil
+}
+
+// leafsDiffer returns true if the leaves l1 and l2 have feature i defined with
+// different values.
+func leafsDiffer(l1, l2 query.QConst, i int) bool {
+	return l1.Val[i] != query.BOT &&
+		l2.Val[i] != query.BOT &&
+		l1.Val[i] != l2.Val[i]
+}
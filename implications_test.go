@@ -0,0 +1,357 @@
+package main
+
+import (
+	"math/rand"
+	"slices"
+	"testing"
+
+	"github.com/jtcaraball/goexpdt/cnf"
+	"github.com/jtcaraball/goexpdt/compute"
+	"github.com/jtcaraball/goexpdt/query"
+	"github.com/jtcaraball/goexpdt/query/logop"
+)
+
+// thresholdModel is a model over a single real feature x split on the
+// thresholds 2, 4 and 6, binarized as the features x > 2, x > 4 and x > 6.
+// Instances with 2 < x <= 4 or x > 6 are positive.
+type thresholdModel struct {
+	nodes []query.Node
+}
+
+func (m thresholdModel) Dim() int { return 3 }
+
+func (m thresholdModel) Nodes() []query.Node { return m.nodes }
+
+func (m thresholdModel) Implications() [][2]int {
+	return [][2]int{{1, 0}, {2, 1}}
+}
+
+var thresholdTree = thresholdModel{
+	nodes: []query.Node{
+		{Feat: 1, ZChild: 1, OChild: 2},
+		{Feat: 0, ZChild: 3, OChild: 4},
+		{Feat: 2, ZChild: 5, OChild: 6},
+		{Value: false, ZChild: -1, OChild: -1},
+		{Value: true, ZChild: -1, OChild: -1},
+		{Value: false, ZChild: -1, OChild: -1},
+		{Value: true, ZChild: -1, OChild: -1},
+	},
+}
+
+func newConst(t *testing.T, s string) query.QConst {
+	t.Helper()
+	c := query.AllBotConst(len(s))
+	if err := sToC(s, c); err != nil {
+		t.Fatalf("Failed to parse constant %s: %s", s, err.Error())
+	}
+	return c
+}
+
+func TestClosedConst(t *testing.T) {
+	tests := []struct {
+		name   string
+		c      string
+		closed string
+		ok     bool
+	}{
+		{name: "undefined", c: "___", closed: "___", ok: true},
+		{name: "one implies lower", c: "__1", closed: "111", ok: true},
+		{name: "zero implies higher", c: "0__", closed: "000", ok: true},
+		{name: "both ways", c: "_1_", closed: "11_", ok: true},
+		{name: "full", c: "100", closed: "100", ok: true},
+		{name: "contradiction", c: "0_1", ok: false},
+		{name: "consecutive contradiction", c: "01_", ok: false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			closed, ok := closedConst(
+				newConst(t, test.c),
+				thresholdTree.Implications(),
+			)
+			if ok != test.ok {
+				t.Fatalf("Expected ok %t but got %t", test.ok, ok)
+			}
+			if ok && closed.AsString() != test.closed {
+				t.Errorf(
+					"Expected closure %s but got %s",
+					test.closed,
+					closed.AsString(),
+				)
+			}
+		})
+	}
+}
+
+func TestImpliedLeafs(t *testing.T) {
+	// Add a split on x > 2 below x > 4 being ONE, whose ZERO branch can not
+	// be reached.
+	nodes := slices.Clone(thresholdTree.nodes)
+	nodes[6] = query.Node{Feat: 0, ZChild: 7, OChild: 8}
+	nodes = append(
+		nodes,
+		query.Node{Value: false, ZChild: -1, OChild: -1},
+		query.Node{Value: true, ZChild: -1, OChild: -1},
+	)
+	ctx := treeQContext(thresholdModel{nodes: nodes})
+
+	pleaf, nleaf, err := impliedLeafs(ctx, implications(ctx))
+	if err != nil {
+		t.Fatalf("Failed to compute leafs: %s", err.Error())
+	}
+
+	asStrings := func(cs []query.QConst) []string {
+		s := make([]string, len(cs))
+		for i, c := range cs {
+			s[i] = c.AsString()
+		}
+		return s
+	}
+	if expected := []string{"100", "111"}; !slices.Equal(
+		expected,
+		asStrings(pleaf),
+	) {
+		t.Errorf(
+			"Positive leafs not equal.\nExpected %v\nbut got  %v",
+			expected,
+			asStrings(pleaf),
+		)
+	}
+	if expected := []string{"000", "110"}; !slices.Equal(
+		expected,
+		asStrings(nleaf),
+	) {
+		t.Errorf(
+			"Negative leafs not equal.\nExpected %v\nbut got  %v",
+			expected,
+			asStrings(nleaf),
+		)
+	}
+}
+
+func TestImpliedDFSConst(t *testing.T) {
+	tests := []struct {
+		name   string
+		c      string
+		dfs    bool
+		binDFS bool
+	}{
+		// x > 6 forces x > 2 and x > 4 so every completion is positive.
+		{name: "x > 6", c: "__1", dfs: true},
+		// x <= 2 forces x <= 4 and x <= 6 so every completion is negative.
+		{name: "x <= 2", c: "0__", dfs: true},
+		// 4 < x <= 6 forces x > 2.
+		{name: "4 < x <= 6", c: "_10", dfs: true},
+		{name: "x > 4", c: "_1_"},
+		{name: "full", c: "110", dfs: true, binDFS: true},
+	}
+
+	ctx := treeQContext(thresholdTree)
+	binCtx := query.BasicQContext(thresholdTree)
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			c := newConst(t, test.c)
+			for _, check := range []struct {
+				name     string
+				ctx      query.QContext
+				expected bool
+			}{
+				{"dfs", ctx, test.dfs},
+				{"binary dfs", binCtx, test.binDFS},
+			} {
+				ncnf, err := impliedDFSConst{I: c}.Encoding(check.ctx)
+				if err != nil {
+					t.Fatalf("Failed to encode %s: %s", check.name, err.Error())
+				}
+				if ncnf.TriviallyTrue() != check.expected {
+					t.Errorf(
+						"Expected %s of %s to be %t",
+						check.name,
+						test.c,
+						check.expected,
+					)
+				}
+			}
+		})
+	}
+}
+
+func TestImpliedVar(t *testing.T) {
+	ctx := treeQContext(thresholdTree)
+	v := query.QVar("x")
+	f := logop.WithVar{
+		I: v,
+		Q: impliedVar{I: v, Implications: thresholdTree.Implications()},
+	}
+
+	for _, c := range allConsts(thresholdTree.Dim()) {
+		closed, ok := closedConst(c, thresholdTree.Implications())
+		expected := ok && closed.AsString() == c.AsString()
+		if sat := satisfiableWith(t, f, ctx, v, c); sat != expected {
+			t.Errorf("Expected %s to be allowed %t", c.AsString(), expected)
+		}
+	}
+}
+
+func TestImpliedQuery(t *testing.T) {
+	// The sufficient reasons of x > 6 are the partial instances subsumed by
+	// it whose completions are all positive. Without implications 1_1 and
+	// _11 are accepted as if x > 2 and x > 4 could be completed as ZERO.
+	c := newConst(t, "111")
+	v := query.QVar("x")
+	tests := []struct {
+		name     string
+		ctx      query.QContext
+		expected []string
+	}{
+		{"implications", treeQContext(thresholdTree), []string{"111"}},
+		{
+			"binary",
+			query.BasicQContext(thresholdTree),
+			[]string{"111", "1_1", "_11"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			fg, _ := impliedQuery(srFGF(c), llOGF(), test.ctx)
+			var reasons []string
+			for _, x := range allConsts(thresholdTree.Dim()) {
+				if satisfiableWith(t, fg(v), test.ctx, v, x) {
+					reasons = append(reasons, x.AsString())
+				}
+			}
+			slices.Sort(reasons)
+			slices.Sort(test.expected)
+			if !slices.Equal(test.expected, reasons) {
+				t.Errorf(
+					"Reasons not equal.\nExpected %v\nbut got  %v",
+					test.expected,
+					reasons,
+				)
+			}
+		})
+	}
+}
+
+func TestRandConst_Implications(t *testing.T) {
+	r := rand.New(rand.NewSource(0))
+	imps := thresholdTree.Implications()
+	c := query.AllBotConst(thresholdTree.Dim())
+
+	for i := 0; i < 100; i++ {
+		for _, full := range []bool{true, false} {
+			randConst(c, full, imps, r)
+			if full && !c.IsFull() {
+				t.Fatalf("Expected full instance but got %s", c.AsString())
+			}
+			closed, ok := closedConst(c, imps)
+			if !ok || closed.AsString() != c.AsString() {
+				t.Fatalf(
+					"Instance %s is not closed under the implications",
+					c.AsString(),
+				)
+			}
+		}
+	}
+}
+
+// allConsts returns every partial instance of dimension dim.
+func allConsts(dim int) []query.QConst {
+	cs := []query.QConst{query.AllBotConst(dim)}
+	for i := 0; i < dim; i++ {
+		var next []query.QConst
+		for _, c := range cs {
+			for _, ft := range []query.FeatV{query.ZERO, query.ONE, query.BOT} {
+				nc := query.QConst{Val: slices.Clone(c.Val)}
+				nc.Val[i] = ft
+				next = append(next, nc)
+			}
+		}
+		cs = next
+	}
+	return cs
+}
+
+// satisfiableWith returns true if the formula f is satisfiable with the
+// variable v fixed to the value of c.
+func satisfiableWith(
+	t *testing.T,
+	f compute.Encodable,
+	ctx query.QContext,
+	v query.QVar,
+	c query.QConst,
+) bool {
+	t.Helper()
+	ctx.Reset()
+	ncnf, err := f.Encoding(ctx)
+	if err != nil {
+		t.Fatalf("Failed to encode formula: %s", err.Error())
+	}
+	sClauses, cClauses := ncnf.Clauses()
+	clauses := append(slices.Clone(sClauses), cClauses...)
+	for i, ft := range c.Val {
+		clauses = append(clauses, cnf.Clause{ctx.CNFVar(v, i, int(ft))})
+	}
+	return satisfiable(clauses)
+}
+
+// satisfiable returns true if the clauses are satisfiable.
+func satisfiable(clauses []cnf.Clause) bool {
+	_, ok := solveClauses(clauses)
+	return ok
+}
+
+// solveClauses returns the literals set to true by an assignment satisfying
+// the clauses, leaving unset the variables it does not depend on. Returns
+// false if the clauses are unsatisfiable. It is a plain DPLL procedure meant
+// for the small formulas of tests.
+func solveClauses(clauses []cnf.Clause) ([]int, bool) {
+	var model []int
+	for {
+		if len(clauses) == 0 {
+			return model, true
+		}
+		unit := 0
+		for _, cl := range clauses {
+			if len(cl) == 0 {
+				return nil, false
+			}
+			if len(cl) == 1 {
+				unit = cl[0]
+				break
+			}
+		}
+		if unit == 0 {
+			break
+		}
+		clauses = assignLit(clauses, unit)
+		model = append(model, unit)
+	}
+	l := clauses[0][0]
+	for _, lit := range []int{l, -l} {
+		if m, ok := solveClauses(assignLit(clauses, lit)); ok {
+			return append(append(model, lit), m...), true
+		}
+	}
+	return nil, false
+}
+
+// assignLit returns the clauses simplified by setting the literal l to true.
+func assignLit(clauses []cnf.Clause, l int) []cnf.Clause {
+	var simplified []cnf.Clause
+	for _, cl := range clauses {
+		if slices.Contains(cl, l) {
+			continue
+		}
+		nc := cnf.Clause{}
+		for _, ol := range cl {
+			if ol != -l {
+				nc = append(nc, ol)
+			}
+		}
+		simplified = append(simplified, nc)
+	}
+	return simplified
+}
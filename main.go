@@ -1,8 +1,14 @@
 package main
 
 import (
+	"bytes"
+	"encoding/json"
+	"flag"
 	"fmt"
+	"io"
 	"os"
+	"path"
+	"time"
 )
 
 func main() {
@@ -27,17 +33,44 @@ func main() {
 		handleList(commandArgs)
 	case "info":
 		handleInfo(commandArgs)
+	case "run":
+		handleRun(commandArgs)
+	case "summarize":
+		handleSummarize(commandArgs)
+	case "plot":
+		handlePlot(commandArgs)
+	case "repl":
+		handleREPL(commandArgs)
+	case "validate":
+		handleValidate(commandArgs)
+	case "tree-info":
+		handleTreeInfo(commandArgs)
+	case "export":
+		handleExport(commandArgs)
+	case "transform":
+		handleTransform(commandArgs)
 	default:
 		handleExperiment(command, commandArgs)
 	}
 }
 
-// handleList writes to stdout the list of available experiments.
+// handleList writes to stdout the list of available experiments. With the
+// --json flag the experiments are written as a json array including their
+// argument schemas.
 func handleList(cArgs []string) {
-	if cArgs != nil {
-		fmt.Println("Command 'list' does not take arguments.")
+	asJSON := len(cArgs) == 1 && cArgs[0] == "--json"
+	if cArgs != nil && !asJSON {
+		fmt.Println("Command 'list' only takes the --json flag.")
 		os.Exit(1)
 	}
+	if asJSON {
+		infos := make([]expInfo, len(experiments))
+		for i, exp := range experiments {
+			infos[i] = exp.Info()
+		}
+		writeJSON(infos)
+		os.Exit(0)
+	}
 	fmt.Println("\nExperiments:")
 	for _, exp := range experiments {
 		fmt.Printf("  - %s\n", exp.Name)
@@ -45,28 +78,274 @@ func handleList(cArgs []string) {
 	os.Exit(0)
 }
 
-// handleInfo writes to stdout the information related to the experiment
-// denoted by cArgs[0].
+// handleInfo writes to stdout the information related to the experiments
+// denoted by cArgs. With a leading --json flag the information is written as
+// a json array.
 func handleInfo(cArgs []string) {
-	if cArgs == nil {
+	asJSON := len(cArgs) > 0 && cArgs[0] == "--json"
+	if asJSON {
+		cArgs = cArgs[1:]
+	}
+	if len(cArgs) == 0 {
 		fmt.Println("Command 'info' requires experiment names.")
 		os.Exit(1)
 	}
 	expLookup := expMap()
-	out := ""
+	infos := []expInfo{}
 	for _, arg := range cArgs {
 		exp, ok := expLookup[arg]
 		if !ok {
 			fmt.Printf("Experiment '%s' does not exist.\n", arg)
 			os.Exit(1)
 		}
-		out += exp.Description
+		infos = append(infos, exp.Info())
+	}
+	if asJSON {
+		writeJSON(infos)
+		os.Exit(0)
+	}
+	out := ""
+	for _, info := range infos {
+		out += info.Description + "\n" + argsHelp(info.Args) + "\n"
 	}
 	fmt.Println()
 	fmt.Println(out)
 	os.Exit(0)
 }
 
+// writeJSON writes v to stdout encoded as indented json.
+func writeJSON(v any) {
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(v); err != nil {
+		fmt.Printf("Error: %s.\n", err.Error())
+		os.Exit(1)
+	}
+}
+
+// handleSummarize writes to stdout the summary of the experiment outputs
+// passed in cArgs and saves it as csv to the --out path, defaulting to a
+// timestamped file in the output directory.
+func handleSummarize(cArgs []string) {
+	var sPath string
+
+	fs := flag.NewFlagSet("summarize", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	fs.StringVar(
+		&sPath,
+		"out",
+		path.Join(
+			outputdir,
+			"summary_"+dateTimeAsString(time.Now())+".csv",
+		),
+		"summary output path",
+	)
+	if err := fs.Parse(cArgs); err != nil {
+		fmt.Printf("Error: %s.\n", err.Error())
+		os.Exit(1)
+	}
+
+	if err := runSummarize(os.Stdout, fs.Args(), sPath); err != nil {
+		fmt.Printf("Error: %s.\n", err.Error())
+		os.Exit(1)
+	}
+	fmt.Printf("\nSummary written to %s.\n", sPath)
+	os.Exit(0)
+}
+
+// handlePlot renders the charts of the experiment outputs passed in cArgs to
+// the --out-dir directory, defaulting to a timestamped directory in the output
+// directory, as images in the --format format.
+func handlePlot(cArgs []string) {
+	var dir, format string
+
+	fs := flag.NewFlagSet("plot", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	fs.StringVar(
+		&dir,
+		"out-dir",
+		path.Join(outputdir, "plots_"+dateTimeAsString(time.Now())),
+		"plots output directory",
+	)
+	fs.StringVar(&format, "format", plotSVG, "image format")
+	if err := fs.Parse(cArgs); err != nil {
+		fmt.Printf("Error: %s.\n", err.Error())
+		os.Exit(1)
+	}
+
+	written, err := runPlot(fs.Args(), dir, format)
+	if err != nil {
+		fmt.Printf("Error: %s.\n", err.Error())
+		os.Exit(1)
+	}
+	fmt.Println("Plots written:")
+	for _, fp := range written {
+		fmt.Printf("  - %s\n", fp)
+	}
+	os.Exit(0)
+}
+
+// handleREPL starts an interactive query session over the tree file passed as
+// the last element of cArgs. Run options may precede the tree file.
+func handleREPL(cArgs []string) {
+	opts, args, err := parseRunOpts("repl", cArgs)
+	if err != nil {
+		fmt.Printf("Error: %s.\n", err.Error())
+		os.Exit(1)
+	}
+	if len(args) != 1 {
+		fmt.Println("Command 'repl' requires exactly one tree file.")
+		os.Exit(1)
+	}
+
+	if err := runREPL(os.Stdin, os.Stdout, args[0], opts); err != nil {
+		fmt.Printf("Error: %s.\n", err.Error())
+		os.Exit(1)
+	}
+	os.Exit(0)
+}
+
+// handleValidate writes to stdout the problems found in the tree files passed
+// in cArgs, exiting with an error status if any of them can not be loaded.
+func handleValidate(cArgs []string) {
+	if len(cArgs) == 0 {
+		fmt.Println("Command 'validate' requires tree files.")
+		os.Exit(1)
+	}
+	if invalid := runValidate(os.Stdout, cArgs); invalid > 0 {
+		fmt.Printf("\n%d of %d files are invalid.\n", invalid, len(cArgs))
+		os.Exit(1)
+	}
+	os.Exit(0)
+}
+
+// handleTreeInfo writes to stdout the structural statistics of the tree files
+// passed in cArgs. With a leading --json flag the statistics are written as a
+// json array.
+func handleTreeInfo(cArgs []string) {
+	asJSON := len(cArgs) > 0 && cArgs[0] == "--json"
+	if asJSON {
+		cArgs = cArgs[1:]
+	}
+	if len(cArgs) == 0 {
+		fmt.Println("Command 'tree-info' requires tree files.")
+		os.Exit(1)
+	}
+	if err := runTreeInfo(os.Stdout, cArgs, asJSON); err != nil {
+		fmt.Printf("Error: %s.\n", err.Error())
+		os.Exit(1)
+	}
+	os.Exit(0)
+}
+
+// handleExport writes the tree file passed as the last element of cArgs in
+// the --format format to the --out path, defaulting to stdout, highlighting
+// the nodes consistent with the --instance instance if set.
+func handleExport(cArgs []string) {
+	var format, inst, outFP string
+
+	fs := flag.NewFlagSet("export", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	fs.StringVar(&format, "format", exportDOT, "export format")
+	fs.StringVar(&inst, "instance", "", "instance to highlight")
+	fs.StringVar(&outFP, "out", "", "export output path")
+	if err := fs.Parse(cArgs); err != nil {
+		fmt.Printf("Error: %s.\n", err.Error())
+		os.Exit(1)
+	}
+	if fs.NArg() != 1 {
+		fmt.Println("Command 'export' requires exactly one tree file.")
+		os.Exit(1)
+	}
+	if format != exportDOT && format != exportMermaid {
+		fmt.Printf("Error: Unknown export format '%s'.\n", format)
+		os.Exit(1)
+	}
+
+	// The export is built in memory so no output file is left on failure.
+	var out bytes.Buffer
+	if err := runExport(&out, fs.Arg(0), format, inst); err != nil {
+		fmt.Printf("Error: %s.\n", err.Error())
+		os.Exit(1)
+	}
+	if err := writeOutput(outFP, out.Bytes()); err != nil {
+		fmt.Printf("Error: %s.\n", err.Error())
+		os.Exit(1)
+	}
+}
+
+// handleTransform writes the tree derived from the tree file passed as the
+// last element of cArgs by the transformations set by the --depth, --collapse
+// and --project flags to the --out path, defaulting to stdout.
+func handleTransform(cArgs []string) {
+	var (
+		opts  transformOpts
+		outFP string
+	)
+
+	fs := flag.NewFlagSet("transform", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	fs.IntVar(&opts.Depth, "depth", -1, "depth to truncate the tree to")
+	fs.BoolVar(&opts.Collapse, "collapse", false, "collapse redundant splits")
+	fs.BoolVar(&opts.Project, "project", false, "drop unused features")
+	fs.StringVar(&outFP, "out", "", "transformed tree output path")
+	if err := fs.Parse(cArgs); err != nil {
+		fmt.Printf("Error: %s.\n", err.Error())
+		os.Exit(1)
+	}
+	if fs.NArg() != 1 {
+		fmt.Println("Command 'transform' requires exactly one tree file.")
+		os.Exit(1)
+	}
+
+	// The tree is encoded in memory so no output file is left on failure.
+	var out bytes.Buffer
+	if err := runTransform(&out, fs.Arg(0), opts); err != nil {
+		fmt.Printf("Error: %s.\n", err.Error())
+		os.Exit(1)
+	}
+	if err := writeOutput(outFP, out.Bytes()); err != nil {
+		fmt.Printf("Error: %s.\n", err.Error())
+		os.Exit(1)
+	}
+}
+
+// handleRun runs the experiment defined in the spec file passed as the last
+// element of cArgs. Run options may precede the spec file.
+func handleRun(cArgs []string) {
+	opts, args, err := parseRunOpts("run", cArgs)
+	if err != nil {
+		fmt.Printf("Error: %s.\n", err.Error())
+		os.Exit(1)
+	}
+	if len(args) != 1 {
+		fmt.Println("Command 'run' requires exactly one spec file.")
+		os.Exit(1)
+	}
+
+	spec, err := loadSpec(args[0])
+	if err != nil {
+		fmt.Printf("Error: %s.\n", err.Error())
+		os.Exit(1)
+	}
+
+	exp, opts, expArgs, err := spec.experiment(opts)
+	if err != nil {
+		fmt.Printf("Error: %s.\n", err.Error())
+		os.Exit(1)
+	}
+
+	fmt.Println("Running experiment...")
+
+	if err := exp.Run(opts, expArgs...); err != nil {
+		fmt.Printf("Error: %s.", err.Error())
+		os.Exit(1)
+	}
+
+	fmt.Println("Done running.")
+	os.Exit(0)
+}
+
 // handleExperiment runs the experiment denoted by c with arguments cArgs.
 func handleExperiment(c string, cArgs []string) {
 	exp, ok := expMap()[c]
@@ -75,9 +354,15 @@ func handleExperiment(c string, cArgs []string) {
 		os.Exit(1)
 	}
 
+	opts, args, err := parseRunOpts(c, cArgs)
+	if err != nil {
+		fmt.Printf("Error: %s.\n", err.Error())
+		os.Exit(1)
+	}
+
 	fmt.Println("Running experiment...")
 
-	if err := exp.Run(cArgs...); err != nil {
+	if err := exp.Run(opts, args...); err != nil {
 		fmt.Printf("Error: %s.", err.Error())
 		os.Exit(1)
 	}
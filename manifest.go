@@ -0,0 +1,273 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"time"
+)
+
+// Manifest run statuses.
+const (
+	manifestRunning = "running"
+	manifestOK      = "ok"
+	manifestError   = "error"
+)
+
+// solverVersionTimeout bounds the time spent querying the solver version.
+const solverVersionTimeout = 5 * time.Second
+
+// runManifest records the provenance of a single experiment run. It is
+// written as a json sidecar next to the run's output.
+type runManifest struct {
+	Command     []string        `json:"command"`
+	Experiment  string          `json:"experiment"`
+	Driver      string          `json:"driver"`
+	Output      string          `json:"output"`
+	Format      string          `json:"format"`
+	TreeStats   bool            `json:"tree_stats"`
+	Resumed     bool            `json:"resumed"`
+	Inputs      []manifestInput `json:"inputs"`
+	Solver      manifestSolver  `json:"solver"`
+	Seed        int64           `json:"seed"`
+	Timeout     string          `json:"timeout"`
+	CallTimeout string          `json:"call_timeout"`
+	Jobs        int             `json:"jobs"`
+	Positive    string          `json:"positive"`
+	Target      string          `json:"target"`
+	GoVersion   string          `json:"go_version"`
+	Host        manifestHost    `json:"host"`
+	Start       time.Time       `json:"start"`
+	End         *time.Time      `json:"end,omitempty"`
+	Status      string          `json:"status"`
+	Error       string          `json:"error,omitempty"`
+}
+
+// manifestInput identifies an input file by path and content hash. Role is
+// the name of the argument the file was passed as, "tree" for the trees
+// referenced by optimization files or "spec" for the spec file defining the
+// experiment.
+type manifestInput struct {
+	Role   string `json:"role"`
+	Path   string `json:"path"`
+	SHA256 string `json:"sha256"`
+}
+
+// manifestSolver identifies the solver executable used by the run.
+type manifestSolver struct {
+	Name    string `json:"name"`
+	Path    string `json:"path"`
+	Version string `json:"version"`
+	SHA256  string `json:"sha256"`
+}
+
+// manifestHost describes the machine the run was executed on.
+type manifestHost struct {
+	Hostname string `json:"hostname"`
+	OS       string `json:"os"`
+	Arch     string `json:"arch"`
+	CPU      string `json:"cpu"`
+	NumCPU   int    `json:"num_cpu"`
+}
+
+// newManifest returns the manifest of a run of experiment e with options opts
+// and parsed arguments args writing to the output file passed by output.
+func newManifest(
+	e experiment,
+	opts runOpts,
+	args expArgs,
+	output string,
+) (*runManifest, error) {
+	m := &runManifest{
+		Command:     os.Args,
+		Experiment:  e.Name,
+		Driver:      e.d.Kind(),
+		Output:      absPath(output),
+		Format:      opts.Format,
+		TreeStats:   opts.TreeStats,
+		Resumed:     opts.resumed != nil,
+		Solver:      solverInfo(opts.Solver),
+		Seed:        opts.Seed,
+		Timeout:     opts.Timeout.String(),
+		CallTimeout: opts.CallTimeout.String(),
+		Jobs:        opts.Jobs,
+		Positive:    opts.Positive,
+		Target:      opts.Target,
+		GoVersion:   runtime.Version(),
+		Host:        hostInfo(),
+		Start:       time.Now(),
+		Status:      manifestRunning,
+	}
+
+	if opts.spec != "" {
+		in, err := hashInput("spec", opts.spec)
+		if err != nil {
+			return nil, err
+		}
+		m.Inputs = append(m.Inputs, in)
+	}
+
+	for _, spec := range e.d.Args() {
+		if spec.Type != argFiles {
+			continue
+		}
+		for _, f := range args.Files(spec.Name) {
+			in, err := hashInput(spec.Name, f)
+			if err != nil {
+				return nil, err
+			}
+			m.Inputs = append(m.Inputs, in)
+
+			// Optimization files reference the tree they are evaluated on.
+			if spec.Name != valArgs[0].Name {
+				continue
+			}
+			treeFP, _, err := scanTIFile(f)
+			if err != nil {
+				return nil, err
+			}
+			if in, err = hashInput("tree", treeFP); err != nil {
+				return nil, err
+			}
+			m.Inputs = append(m.Inputs, in)
+		}
+	}
+
+	return m, nil
+}
+
+// manifestPath returns the path of the manifest of a run writing to output.
+// Resumed runs get their own manifest so that of the original run is kept.
+func manifestPath(output string, resumed bool, start time.Time) string {
+	base := strings.TrimSuffix(output, filepath.Ext(output))
+	if resumed {
+		base += ".resume_" + dateTimeAsString(start)
+	}
+	return base + ".manifest.json"
+}
+
+// Write writes the manifest as indented json to the file passed by path.
+func (m *runManifest) Write(path string) error {
+	b, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(b, '\n'), 0o644)
+}
+
+// Finish records the end time of the run and its exit status given the error
+// it returned.
+func (m *runManifest) Finish(err error) {
+	end := time.Now()
+	m.End = &end
+	m.Status = manifestOK
+	if err != nil {
+		m.Status = manifestError
+		m.Error = err.Error()
+	}
+}
+
+// hashInput returns the manifest entry of the input file passed by path.
+func hashInput(role, path string) (manifestInput, error) {
+	sum, err := fileSHA256(path)
+	if err != nil {
+		return manifestInput{}, err
+	}
+	return manifestInput{Role: role, Path: absPath(path), SHA256: sum}, nil
+}
+
+// fileSHA256 returns the hex encoded sha256 hash of the contents of the file
+// passed by path.
+func fileSHA256(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	if _, err = io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// absPath returns the absolute version of path or path itself if it can not
+// be resolved.
+func absPath(path string) string {
+	if abs, err := filepath.Abs(path); err == nil {
+		return abs
+	}
+	return path
+}
+
+// solverInfo returns the manifest entry of solver s. The executable hash and
+// version are left empty if they can not be determined.
+func solverInfo(s satSolver) manifestSolver {
+	info := manifestSolver{Name: s.Name, Path: s.Path}
+
+	path, err := exec.LookPath(s.Path)
+	if err != nil {
+		return info
+	}
+	info.Path = absPath(path)
+	info.SHA256, _ = fileSHA256(path)
+
+	ctx, cancel := context.WithTimeout(
+		context.Background(),
+		solverVersionTimeout,
+	)
+	defer cancel()
+	out, err := exec.CommandContext(ctx, path, "--version").Output()
+	if err != nil {
+		return info
+	}
+	info.Version = firstLine(out)
+
+	return info
+}
+
+// hostInfo returns the description of the current machine.
+func hostInfo() manifestHost {
+	h := manifestHost{
+		OS:     runtime.GOOS,
+		Arch:   runtime.GOARCH,
+		NumCPU: runtime.NumCPU(),
+	}
+	h.Hostname, _ = os.Hostname()
+
+	// CPU model names are only available on linux.
+	b, err := os.ReadFile("/proc/cpuinfo")
+	if err != nil {
+		return h
+	}
+	scanner := bufio.NewScanner(bytes.NewReader(b))
+	for scanner.Scan() {
+		k, v, ok := strings.Cut(scanner.Text(), ":")
+		if ok && strings.TrimSpace(k) == "model name" {
+			h.CPU = strings.TrimSpace(v)
+			break
+		}
+	}
+
+	return h
+}
+
+// firstLine returns the first non empty line of b.
+func firstLine(b []byte) string {
+	for _, l := range strings.Split(string(b), "\n") {
+		if l = strings.TrimSpace(l); l != "" {
+			return l
+		}
+	}
+	return ""
+}
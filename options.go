@@ -0,0 +1,180 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"time"
+)
+
+// runOpts holds the options shared by every experiment run.
+type runOpts struct {
+	// Solver used to decide every SAT call of the run.
+	Solver satSolver
+	// Seed of the random instance generator.
+	Seed int64
+	// Timeout is the wall-clock budget of a single query and CallTimeout that
+	// of a single solver call. Zero values impose no bound.
+	Timeout     time.Duration
+	CallTimeout time.Duration
+	// Jobs is the number of instances evaluated in parallel.
+	Jobs int
+	// Positive is the class treated as positive by the trees of the run. The
+	// trees' own positive class is used if empty and every class in turn if
+	// equal to allClasses.
+	Positive string
+	// Target is the classification of the random instances explained by the
+	// run: one of targetPositive, targetNegative or targetBoth.
+	Target string
+	// Format of the results output: one of "csv", "jsonl" or "parquet".
+	Format string
+	// TreeStats adds the depth and number of leafs of the trees to the
+	// results.
+	TreeStats bool
+	// Resume is the path of a partial output to continue.
+	Resume string
+	// resumed holds the progress of the output being resumed.
+	resumed *resumeState
+	// spec is the path of the spec file defining the experiment run, if
+	// any.
+	spec string
+}
+
+// allClasses is the Positive option value that runs experiments once per
+// class of each tree.
+const allClasses = "all"
+
+// Target option values.
+const (
+	targetPositive = "positive"
+	targetNegative = "negative"
+	targetBoth     = "both"
+)
+
+// isTarget returns true if t is a valid Target option value.
+func isTarget(t string) bool {
+	return t == targetPositive || t == targetNegative || t == targetBoth
+}
+
+// Environment variables that provide default values for run options.
+const (
+	envSolver      = "GOEXPDT_SOLVER"
+	envSolverPath  = "GOEXPDT_SOLVER_PATH"
+	envSolverArgs  = "GOEXPDT_SOLVER_ARGS"
+	envSolverCodes = "GOEXPDT_SOLVER_CODES"
+)
+
+// parseRunOpts parses the run options at the start of args, falling back to
+// the environment for unset values. Returns the options and the remaining
+// positional arguments.
+func parseRunOpts(name string, args []string) (runOpts, []string, error) {
+	var (
+		opts        runOpts
+		solverName  string
+		solverPath  string
+		solverArgs  string
+		solverCodes string
+	)
+
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	fs.StringVar(
+		&solverName,
+		"solver",
+		envOr(envSolver, defaultSolver),
+		"SAT solver name",
+	)
+	fs.Int64Var(
+		&opts.Seed,
+		"seed",
+		time.Now().UnixNano(),
+		"random instance generator seed",
+	)
+	fs.StringVar(
+		&solverPath,
+		"solver-path",
+		os.Getenv(envSolverPath),
+		"SAT solver executable path",
+	)
+	fs.StringVar(
+		&solverArgs,
+		"solver-args",
+		os.Getenv(envSolverArgs),
+		"space separated SAT solver arguments",
+	)
+	fs.StringVar(
+		&solverCodes,
+		"solver-codes",
+		os.Getenv(envSolverCodes),
+		"SAT solver exit codes as <sat>,<unsat>",
+	)
+
+	fs.DurationVar(
+		&opts.Timeout,
+		"timeout",
+		0,
+		"wall-clock budget per query",
+	)
+	fs.DurationVar(
+		&opts.CallTimeout,
+		"call-timeout",
+		0,
+		"wall-clock budget per solver call",
+	)
+
+	fs.IntVar(&opts.Jobs, "jobs", 1, "instances evaluated in parallel")
+	fs.StringVar(
+		&opts.Positive,
+		"positive",
+		"",
+		"positive class or 'all' to run over every class",
+	)
+	fs.StringVar(
+		&opts.Target,
+		"target",
+		targetPositive,
+		"classification of random instances: positive, negative or both",
+	)
+	fs.StringVar(&opts.Format, "format", formatCSV, "results output format")
+	fs.BoolVar(
+		&opts.TreeStats,
+		"tree-stats",
+		false,
+		"add tree depth and leaf count columns",
+	)
+	fs.StringVar(&opts.Resume, "resume", "", "partial output to continue")
+
+	if err := fs.Parse(args); err != nil {
+		return runOpts{}, nil, err
+	}
+	if opts.Jobs < 1 {
+		return runOpts{}, nil, errors.New("Jobs must be positive")
+	}
+	if !isTarget(opts.Target) {
+		return runOpts{}, nil, fmt.Errorf("Unknown target '%s'", opts.Target)
+	}
+	if !isFormat(opts.Format) {
+		return runOpts{}, nil, fmt.Errorf("Unknown format '%s'", opts.Format)
+	}
+
+	s, err := lookupSolver(solverName, solverPath)
+	if err != nil {
+		return runOpts{}, nil, err
+	}
+	if opts.Solver, err = s.configure(solverArgs, solverCodes); err != nil {
+		return runOpts{}, nil, err
+	}
+
+	return opts, fs.Args(), nil
+}
+
+// envOr returns the value of the environment variable key or def if it is
+// unset or empty.
+func envOr(key, def string) string {
+	if v := os.Getenv(key); v != "" {
+		return v
+	}
+	return def
+}
@@ -40,6 +40,27 @@ func ssOGF() compute.VCOrder {
 	}
 }
 
+// glOGF returns a query generator for the strict partial order Greater
+// Level, preferring instances with fewer undefined features.
+func glOGF() compute.VCOrder {
+	return func(v query.QVar, c query.QConst) compute.Encodable {
+		return logop.And{
+			Q1: lel.ConstVar{
+				I1:          c,
+				I2:          v,
+				CountVarGen: varGenBotCount,
+			},
+			Q2: logop.Not{
+				Q: lel.VarConst{
+					I1:          v,
+					I2:          c,
+					CountVarGen: varGenBotCount,
+				},
+			},
+		}
+	}
+}
+
 // lhOGF returns a query generator for the strict partial order Less Hamming
 // Distance.
 func lhOGF(cp query.QConst) compute.VCOrder {
@@ -85,3 +106,108 @@ func ghOGF(cp query.QConst) compute.VCOrder {
 		}
 	}
 }
+
+// cvOrder is a constructor for an encodable formula representing a strict
+// order between instances represented as a query constant and a query
+// variable. It is the reverse of a compute.VCOrder, holding when the constant
+// precedes the variable.
+type cvOrder func(c query.QConst, v query.QVar) compute.Encodable
+
+// llROGF returns a query generator for the reverse of the strict partial order
+// Less Level.
+func llROGF() cvOrder {
+	return func(c query.QConst, v query.QVar) compute.Encodable {
+		return logop.And{
+			Q1: lel.ConstVar{
+				I1:          c,
+				I2:          v,
+				CountVarGen: varGenBotCount,
+			},
+			Q2: logop.Not{
+				Q: lel.VarConst{
+					I1:          v,
+					I2:          c,
+					CountVarGen: varGenBotCount,
+				},
+			},
+		}
+	}
+}
+
+// ssROGF returns a query generator for the reverse of the strict partial order
+// Strict Subsumption.
+func ssROGF() cvOrder {
+	return func(c query.QConst, v query.QVar) compute.Encodable {
+		return logop.And{
+			Q1: subsumption.ConstVar{I1: c, I2: v},
+			Q2: logop.Not{Q: subsumption.VarConst{I1: v, I2: c}},
+		}
+	}
+}
+
+// glROGF returns a query generator for the reverse of the strict partial order
+// Greater Level.
+func glROGF() cvOrder {
+	return func(c query.QConst, v query.QVar) compute.Encodable {
+		return logop.And{
+			Q1: lel.VarConst{
+				I1:          v,
+				I2:          c,
+				CountVarGen: varGenBotCount,
+			},
+			Q2: logop.Not{
+				Q: lel.ConstVar{
+					I1:          c,
+					I2:          v,
+					CountVarGen: varGenBotCount,
+				},
+			},
+		}
+	}
+}
+
+// lhROGF returns a query generator for the reverse of the strict partial order
+// Less Hamming Distance.
+func lhROGF(cp query.QConst) cvOrder {
+	return func(c query.QConst, v query.QVar) compute.Encodable {
+		return logop.And{
+			Q1: leh.ConstConstVar{
+				I1:                    cp,
+				I2:                    c,
+				I3:                    v,
+				HammingDistanceVarGen: varGenHammingDistance,
+			},
+			Q2: logop.Not{
+				Q: leh.ConstVarConst{
+					I1:                    cp,
+					I2:                    v,
+					I3:                    c,
+					HammingDistanceVarGen: varGenHammingDistance,
+				},
+			},
+		}
+	}
+}
+
+// ghROGF returns a query generator for the reverse of the strict partial order
+// Greater Hamming Distance.
+func ghROGF(cp query.QConst) cvOrder {
+	return func(c query.QConst, v query.QVar) compute.Encodable {
+		return logop.And{
+			Q1: leh.ConstVarConst{
+				I1:                    cp,
+				I2:                    v,
+				I3:                    c,
+				HammingDistanceVarGen: varGenHammingDistance,
+			},
+			Q2: logop.Not{
+				Q: leh.ConstConstVar{
+					I1:                    cp,
+					I2:                    c,
+					I3:                    v,
+					HammingDistanceVarGen: varGenHammingDistance,
+				},
+			},
+		}
+	}
+}
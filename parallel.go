@@ -0,0 +1,101 @@
+package main
+
+import (
+	"sync"
+
+	"github.com/jtcaraball/goexpdt/query"
+)
+
+// taskResult is the outcome of evaluating the task with index i.
+type taskResult[T any] struct {
+	i   int
+	val T
+	err error
+}
+
+// runOrdered evaluates the tasks with indexes start to n-1 over a pool of
+// jobs workers and passes their results to write in index order. Every worker
+// owns a query.QContext built with newCtx, which is passed to eval.
+// Evaluation stops at the first error, which is returned once the workers are
+// done.
+func runOrdered[T any](
+	start, n, jobs int,
+	newCtx func() (query.QContext, error),
+	eval func(i int, ctx query.QContext) (T, error),
+	write func(T) error,
+) error {
+	if jobs < 1 {
+		jobs = 1
+	}
+	if jobs > n-start {
+		jobs = n - start
+	}
+
+	tasks := make(chan int)
+	results := make(chan taskResult[T])
+	done := make(chan struct{})
+
+	var wg sync.WaitGroup
+	for j := 0; j < jobs; j++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			ctx, err := newCtx()
+			for i := range tasks {
+				var r taskResult[T]
+				if err != nil {
+					r = taskResult[T]{i: i, err: err}
+				} else {
+					val, err := eval(i, ctx)
+					r = taskResult[T]{i: i, val: val, err: err}
+				}
+				select {
+				case results <- r:
+				case <-done:
+					return
+				}
+			}
+		}()
+	}
+
+	go func() {
+		defer close(tasks)
+		for i := start; i < n; i++ {
+			select {
+			case tasks <- i:
+			case <-done:
+				return
+			}
+		}
+	}()
+
+	go func() {
+		wg.Wait()
+		close(results)
+	}()
+
+	var err error
+	next := start
+	pending := make(map[int]T)
+	for r := range results {
+		if err != nil {
+			continue
+		}
+		if r.err != nil {
+			err = r.err
+			close(done)
+			continue
+		}
+		pending[r.i] = r.val
+		for val, ok := pending[next]; ok; val, ok = pending[next] {
+			delete(pending, next)
+			next += 1
+			if err = write(val); err != nil {
+				close(done)
+				break
+			}
+		}
+	}
+
+	return err
+}
@@ -0,0 +1,155 @@
+package main
+
+import (
+	"errors"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/jtcaraball/goexpdt/query"
+)
+
+func TestRunOrdered(t *testing.T) {
+	tests := []struct {
+		name     string
+		start, n int
+		jobs     int
+	}{
+		{name: "single job", start: 0, n: 10, jobs: 1},
+		{name: "many jobs", start: 0, n: 20, jobs: 4},
+		{name: "more jobs than tasks", start: 0, n: 3, jobs: 8},
+		{name: "no jobs", start: 0, n: 5, jobs: 0},
+		{name: "resumed", start: 6, n: 12, jobs: 3},
+		{name: "no tasks", start: 4, n: 4, jobs: 2},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var (
+				mu   sync.Mutex
+				ctxs int
+			)
+			newCtx := func() (query.QContext, error) {
+				mu.Lock()
+				defer mu.Unlock()
+				ctxs += 1
+				return query.BasicQContext(nil), nil
+			}
+			// Later tasks finish first so results arrive out of order.
+			eval := func(i int, ctx query.QContext) (int, error) {
+				if ctx == nil {
+					return 0, errors.New("nil context")
+				}
+				time.Sleep(time.Duration(test.n-i) * time.Millisecond)
+				return i, nil
+			}
+			var written []int
+			write := func(i int) error {
+				written = append(written, i)
+				return nil
+			}
+
+			err := runOrdered(test.start, test.n, test.jobs, newCtx, eval, write)
+			if err != nil {
+				t.Fatalf("Failed to run tasks: %s", err.Error())
+			}
+
+			var expected []int
+			for i := test.start; i < test.n; i++ {
+				expected = append(expected, i)
+			}
+			if !slices.Equal(expected, written) {
+				t.Errorf(
+					"Results not in order.\nExpected %v\nbut got  %v",
+					expected,
+					written,
+				)
+			}
+			if ctxs > max(test.jobs, 1) {
+				t.Errorf(
+					"Expected at most %d contexts but got %d",
+					max(test.jobs, 1),
+					ctxs,
+				)
+			}
+		})
+	}
+}
+
+func TestRunOrdered_Errors(t *testing.T) {
+	errTask := errors.New("task error")
+	newCtx := func() (query.QContext, error) {
+		return query.BasicQContext(nil), nil
+	}
+
+	t.Run("eval", func(t *testing.T) {
+		eval := func(i int, ctx query.QContext) (int, error) {
+			if i == 5 {
+				return 0, errTask
+			}
+			return i, nil
+		}
+		var written []int
+		write := func(i int) error {
+			written = append(written, i)
+			return nil
+		}
+
+		err := runOrdered(0, 20, 4, newCtx, eval, write)
+		if !errors.Is(err, errTask) {
+			t.Fatalf("Expected task error but got %v", err)
+		}
+		// Only results preceding the failed task may be written.
+		for j, i := range written {
+			if i != j || i >= 5 {
+				t.Fatalf("Unexpected results written %v", written)
+			}
+		}
+	})
+
+	t.Run("write", func(t *testing.T) {
+		eval := func(i int, ctx query.QContext) (int, error) {
+			return i, nil
+		}
+		var written []int
+		write := func(i int) error {
+			if i == 3 {
+				return errTask
+			}
+			written = append(written, i)
+			return nil
+		}
+
+		err := runOrdered(0, 20, 4, newCtx, eval, write)
+		if !errors.Is(err, errTask) {
+			t.Fatalf("Expected write error but got %v", err)
+		}
+		if expected := []int{0, 1, 2}; !slices.Equal(expected, written) {
+			t.Errorf(
+				"Results not equal.\nExpected %v\nbut got  %v",
+				expected,
+				written,
+			)
+		}
+	})
+
+	t.Run("context", func(t *testing.T) {
+		newCtx := func() (query.QContext, error) {
+			return nil, errTask
+		}
+		eval := func(i int, ctx query.QContext) (int, error) {
+			t.Errorf("Unexpected evaluation of task %d", i)
+			return i, nil
+		}
+		write := func(i int) error {
+			t.Errorf("Unexpected write of result %d", i)
+			return nil
+		}
+
+		err := runOrdered(0, 20, 4, newCtx, eval, write)
+		if !errors.Is(err, errTask) {
+			t.Fatalf("Expected context error but got %v", err)
+		}
+	})
+}
@@ -0,0 +1,247 @@
+package main
+
+import (
+	"cmp"
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"slices"
+	"strconv"
+
+	"gonum.org/v1/plot"
+	"gonum.org/v1/plot/plotter"
+	"gonum.org/v1/plot/plotutil"
+	"gonum.org/v1/plot/vg"
+)
+
+// Plot image formats.
+const (
+	plotSVG = "svg"
+	plotPNG = "png"
+)
+
+// Plot dimensions.
+const (
+	plotWidth  = 6 * vg.Inch
+	plotHeight = 4 * vg.Inch
+)
+
+// maxHistBins bounds the number of bins of histograms.
+const maxHistBins = 50
+
+// plotSeries holds the completed rows of the outputs of a single query.
+type plotSeries struct {
+	query string
+	// nodes, time, calls and bots hold the values of the tree_nodes,
+	// "time (ns)", "#calls" and "#bots" columns of every row. Columns missing
+	// from the outputs are left empty.
+	nodes []float64
+	time  []float64
+	calls []float64
+	bots  []float64
+}
+
+// loadPlotSeries returns the series of the csv outputs passed by paths, one
+// per query in order of first appearance. Rows that timed out are skipped.
+func loadPlotSeries(paths []string) ([]*plotSeries, error) {
+	var series []*plotSeries
+
+	for _, p := range paths {
+		rows, err := readOutput(p)
+		if err != nil {
+			return nil, err
+		}
+		header := rows[0]
+
+		q := outputQuery(p)
+		i := slices.IndexFunc(series, func(s *plotSeries) bool {
+			return s.query == q
+		})
+		if i < 0 {
+			series = append(series, &plotSeries{query: q})
+			i = len(series) - 1
+		}
+		s := series[i]
+
+		sCol := slices.Index(header, "status")
+		cols := []struct {
+			name string
+			dst  *[]float64
+		}{
+			{"tree_nodes", &s.nodes},
+			{"time (ns)", &s.time},
+			{"#calls", &s.calls},
+			{"#bots", &s.bots},
+		}
+
+		for _, row := range rows[1:] {
+			if sCol >= 0 && row[sCol] == "timeout" {
+				continue
+			}
+			for _, col := range cols {
+				c := slices.Index(header, col.name)
+				if c < 0 {
+					continue
+				}
+				v, err := strconv.ParseFloat(row[c], 64)
+				if err != nil {
+					return nil, fmt.Errorf(
+						"Output %s: invalid %s value '%s'",
+						p,
+						col.name,
+						row[c],
+					)
+				}
+				*col.dst = append(*col.dst, v)
+			}
+		}
+	}
+
+	return series, nil
+}
+
+// plotTimeVsNodes returns a plot of the mean time in seconds taken by every
+// query against the number of nodes of the trees.
+func plotTimeVsNodes(series []*plotSeries) (*plot.Plot, error) {
+	p := plot.New()
+	p.Title.Text = "Time vs. tree nodes"
+	p.X.Label.Text = "tree nodes"
+	p.Y.Label.Text = "mean time (s)"
+	p.Legend.Top = true
+	p.Legend.Left = true
+
+	var lines []any
+	for _, s := range series {
+		if len(s.time) == 0 {
+			continue
+		}
+		sums := make(map[float64][2]float64)
+		for i, n := range s.nodes {
+			sums[n] = [2]float64{sums[n][0] + s.time[i], sums[n][1] + 1}
+		}
+		xys := make(plotter.XYs, 0, len(sums))
+		for n, sum := range sums {
+			xys = append(xys, plotter.XY{X: n, Y: sum[0] / sum[1] / 1e9})
+		}
+		slices.SortFunc(xys, func(a, b plotter.XY) int {
+			return cmp.Compare(a.X, b.X)
+		})
+		lines = append(lines, s.query, xys)
+	}
+	if len(lines) == 0 {
+		return nil, nil
+	}
+
+	if err := plotutil.AddLinePoints(p, lines...); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// plotCalls returns a box plot of the distribution of the number of solver
+// calls made by every query.
+func plotCalls(series []*plotSeries) (*plot.Plot, error) {
+	p := plot.New()
+	p.Title.Text = "Solver calls"
+	p.Y.Label.Text = "#calls"
+
+	var names []string
+	for _, s := range series {
+		if len(s.calls) == 0 {
+			continue
+		}
+		box, err := plotter.NewBoxPlot(
+			vg.Points(20),
+			float64(len(names)),
+			plotter.Values(s.calls),
+		)
+		if err != nil {
+			return nil, err
+		}
+		p.Add(box)
+		names = append(names, s.query)
+	}
+	if len(names) == 0 {
+		return nil, nil
+	}
+
+	p.NominalX(names...)
+	return p, nil
+}
+
+// plotBots returns a histogram of the number of bottom features in the
+// optimal values found by query s.
+func plotBots(s *plotSeries) (*plot.Plot, error) {
+	if len(s.bots) == 0 {
+		return nil, nil
+	}
+
+	p := plot.New()
+	p.Title.Text = s.query
+	p.X.Label.Text = "#bots"
+	p.Y.Label.Text = "instances"
+
+	bins := int(slices.Max(s.bots)-slices.Min(s.bots)) + 1
+	hist, err := plotter.NewHist(
+		plotter.Values(s.bots),
+		min(bins, maxHistBins),
+	)
+	if err != nil {
+		return nil, err
+	}
+	p.Add(hist)
+
+	return p, nil
+}
+
+// runPlot renders the charts of the csv outputs passed by paths to dir as
+// images in the given format and returns the paths of the written files.
+func runPlot(paths []string, dir, format string) ([]string, error) {
+	if len(paths) == 0 {
+		return nil, errors.New("Missing outputs to plot")
+	}
+	if format != plotSVG && format != plotPNG {
+		return nil, fmt.Errorf("Unknown plot format '%s'", format)
+	}
+
+	series, err := loadPlotSeries(paths)
+	if err != nil {
+		return nil, err
+	}
+
+	plots := make(map[string]*plot.Plot)
+	if plots["time_vs_nodes"], err = plotTimeVsNodes(series); err != nil {
+		return nil, err
+	}
+	if plots["calls"], err = plotCalls(series); err != nil {
+		return nil, err
+	}
+	for _, s := range series {
+		if plots["bots_"+s.query], err = plotBots(s); err != nil {
+			return nil, err
+		}
+	}
+
+	if err = os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+
+	var written []string
+	for name, p := range plots {
+		if p == nil {
+			continue
+		}
+		fp := path.Join(dir, name+"."+format)
+		if err = p.Save(plotWidth, plotHeight, fp); err != nil {
+			return nil, err
+		}
+		written = append(written, fp)
+	}
+	if len(written) == 0 {
+		return nil, errors.New("Outputs have no results to plot")
+	}
+	slices.Sort(written)
+
+	return written, nil
+}
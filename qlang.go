@@ -0,0 +1,536 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"unicode"
+
+	"github.com/jtcaraball/goexpdt/compute"
+	"github.com/jtcaraball/goexpdt/query"
+	"github.com/jtcaraball/goexpdt/query/extensions/allcomp"
+	"github.com/jtcaraball/goexpdt/query/extensions/full"
+	"github.com/jtcaraball/goexpdt/query/extensions/leh"
+	"github.com/jtcaraball/goexpdt/query/logop"
+	"github.com/jtcaraball/goexpdt/query/predicates/lel"
+	"github.com/jtcaraball/goexpdt/query/predicates/subsumption"
+)
+
+// The query language describes formulas and orders over partial instances
+// with the grammar
+//
+//	expr  := and { "|" and }
+//	and   := unary { "&" unary }
+//	unary := "!" unary | "(" expr ")" | pred "(" term { "," term } ")"
+//
+// where the terms are the optimized variable "x", the instance "c" the query
+// is evaluated on and, in orders only, the value "b" the variable is compared
+// against. Decision formulas take the instance "c" and any other identifier
+// as an existentially quantified variable. Predicates are listed in
+// qlPredicates.
+
+// Query language terms.
+const (
+	qlVar      = "x"
+	qlInstance = "c"
+	qlBest     = "b"
+)
+
+// qlTerm is the value a query language term is bound to: either a variable
+// or a constant.
+type qlTerm struct {
+	isVar bool
+	v     query.QVar
+	c     query.QConst
+}
+
+// qlPredicate describes a predicate of the query language.
+type qlPredicate struct {
+	// arity is the number of terms the predicate takes.
+	arity int
+	// build returns the encodable predicate over the bound terms.
+	build func(ts []qlTerm) compute.Encodable
+}
+
+// qlPredicates maps predicate names to their definitions.
+var qlPredicates = map[string]qlPredicate{
+	// subsumed(a, b): a is subsumed by b.
+	"subsumed": {2, qlSubsumed},
+	// lel(a, b): a has more or equal bottoms than b.
+	"lel": {2, qlLEL},
+	// leh(a, b, c): the hamming distance between a and b is less or equal
+	// than the one between a and c.
+	"leh": {3, qlLEH},
+	// full(a): a has no bottoms.
+	"full": {1, func(ts []qlTerm) compute.Encodable {
+		if ts[0].isVar {
+			return full.Var{I: ts[0].v}
+		}
+		return full.Const{I: ts[0].c}
+	}},
+	// dfs(a): every completion of a has the same classification.
+	"dfs": {1, func(ts []qlTerm) compute.Encodable {
+		if ts[0].isVar {
+			return impliedDFS{I: ts[0].v}
+		}
+		return impliedDFSConst{I: ts[0].c}
+	}},
+	// allpos(a): every completion of a is classified as positive.
+	"allpos": {1, func(ts []qlTerm) compute.Encodable {
+		return qlAllComp(ts[0], true)
+	}},
+	// allneg(a): every completion of a is classified as negative.
+	"allneg": {1, func(ts []qlTerm) compute.Encodable {
+		return qlAllComp(ts[0], false)
+	}},
+}
+
+func qlSubsumed(ts []qlTerm) compute.Encodable {
+	a, b := ts[0], ts[1]
+	switch {
+	case a.isVar && b.isVar:
+		return subsumption.VarVar{I1: a.v, I2: b.v}
+	case a.isVar:
+		return subsumption.VarConst{I1: a.v, I2: b.c}
+	case b.isVar:
+		return subsumption.ConstVar{I1: a.c, I2: b.v}
+	default:
+		return subsumption.ConstConst{I1: a.c, I2: b.c}
+	}
+}
+
+func qlLEL(ts []qlTerm) compute.Encodable {
+	a, b := ts[0], ts[1]
+	switch {
+	case a.isVar && b.isVar:
+		return lel.VarVar{I1: a.v, I2: b.v, CountVarGen: varGenBotCount}
+	case a.isVar:
+		return lel.VarConst{I1: a.v, I2: b.c, CountVarGen: varGenBotCount}
+	case b.isVar:
+		return lel.ConstVar{I1: a.c, I2: b.v, CountVarGen: varGenBotCount}
+	default:
+		return lel.ConstConst{I1: a.c, I2: b.c}
+	}
+}
+
+func qlLEH(ts []qlTerm) compute.Encodable {
+	a, b, c := ts[0], ts[1], ts[2]
+	hd, eq := varGenHammingDistance, varGenEqualFeature
+	switch {
+	case !a.isVar && !b.isVar && !c.isVar:
+		return leh.ConstConstConst{I1: a.c, I2: b.c, I3: c.c}
+	case !a.isVar && !b.isVar:
+		return leh.ConstConstVar{
+			I1:                    a.c,
+			I2:                    b.c,
+			I3:                    c.v,
+			HammingDistanceVarGen: hd,
+		}
+	case !a.isVar && !c.isVar:
+		return leh.ConstVarConst{
+			I1:                    a.c,
+			I2:                    b.v,
+			I3:                    c.c,
+			HammingDistanceVarGen: hd,
+		}
+	case !a.isVar:
+		return leh.ConstVarVar{
+			I1:                    a.c,
+			I2:                    b.v,
+			I3:                    c.v,
+			HammingDistanceVarGen: hd,
+		}
+	case !b.isVar && !c.isVar:
+		return leh.VarConstConst{
+			I1:                    a.v,
+			I2:                    b.c,
+			I3:                    c.c,
+			HammingDistanceVarGen: hd,
+		}
+	case !b.isVar:
+		return leh.VarConstVar{
+			I1:                    a.v,
+			I2:                    b.c,
+			I3:                    c.v,
+			HammingDistanceVarGen: hd,
+			EqualFeatureVarGen:    eq,
+		}
+	case !c.isVar:
+		return leh.VarVarConst{
+			I1:                    a.v,
+			I2:                    b.v,
+			I3:                    c.c,
+			HammingDistanceVarGen: hd,
+			EqualFeatureVarGen:    eq,
+		}
+	default:
+		return leh.VarVarVar{
+			I1:                    a.v,
+			I2:                    b.v,
+			I3:                    c.v,
+			HammingDistanceVarGen: hd,
+			EqualFeatureVarGen:    eq,
+		}
+	}
+}
+
+func qlAllComp(t qlTerm, val bool) compute.Encodable {
+	if t.isVar {
+		return allcomp.Var{
+			I:               t.v,
+			LeafValue:       val,
+			ReachNodeVarGen: varGenNodeReach,
+		}
+	}
+	return allcomp.Const{I: t.c, LeafValue: val}
+}
+
+// qlNode is a node of a parsed query language expression. Connective nodes
+// have "&", "|" or "!" as op and operands in args, predicate nodes have the
+// predicate name as op and their terms in terms.
+type qlNode struct {
+	op    string
+	args  []*qlNode
+	terms []string
+}
+
+// encode returns the encodable expression of the node with its terms bound
+// according to env.
+func (n *qlNode) encode(env map[string]qlTerm) compute.Encodable {
+	switch n.op {
+	case "&":
+		return logop.And{Q1: n.args[0].encode(env), Q2: n.args[1].encode(env)}
+	case "|":
+		return logop.Or{Q1: n.args[0].encode(env), Q2: n.args[1].encode(env)}
+	case "!":
+		return logop.Not{Q: n.args[0].encode(env)}
+	default:
+		ts := make([]qlTerm, len(n.terms))
+		for i, t := range n.terms {
+			ts[i] = env[t]
+		}
+		return qlPredicates[n.op].build(ts)
+	}
+}
+
+// qlParser is a recursive descent parser over the tokens of an expression.
+type qlParser struct {
+	src    string
+	tokens []qlToken
+	pos    int
+	// terms are the terms the expression may use.
+	terms []string
+	// freeVars is true if identifiers other than terms are accepted as
+	// variables, which are collected in vars in order of appearance.
+	freeVars bool
+	vars     []string
+}
+
+// qlToken is a lexical token of an expression and its offset in the source.
+type qlToken struct {
+	text string
+	off  int
+}
+
+// parseQL parses the query language expression src allowing only the given
+// terms.
+func parseQL(src string, terms ...string) (*qlNode, error) {
+	p, err := newQLParser(src, terms)
+	if err != nil {
+		return nil, err
+	}
+	return p.parse()
+}
+
+// newQLParser returns a parser over the tokens of src allowing only the given
+// terms.
+func newQLParser(src string, terms []string) (*qlParser, error) {
+	tokens, err := lexQL(src)
+	if err != nil {
+		return nil, err
+	}
+	return &qlParser{src: src, tokens: tokens, terms: terms}, nil
+}
+
+// parse parses the whole expression.
+func (p *qlParser) parse() (*qlNode, error) {
+	n, err := p.expr()
+	if err != nil {
+		return nil, err
+	}
+	if p.pos < len(p.tokens) {
+		return nil, p.errorf("unexpected '%s'", p.peek())
+	}
+	return n, nil
+}
+
+// lexQL splits src into identifiers and punctuation tokens.
+func lexQL(src string) ([]qlToken, error) {
+	var tokens []qlToken
+	for i := 0; i < len(src); {
+		r := rune(src[i])
+		switch {
+		case unicode.IsSpace(r):
+			i += 1
+		case strings.ContainsRune("()!&|,", r):
+			tokens = append(tokens, qlToken{src[i : i+1], i})
+			i += 1
+		case unicode.IsLetter(r):
+			j := i
+			for j < len(src) && (unicode.IsLetter(rune(src[j])) ||
+				unicode.IsDigit(rune(src[j])) ||
+				src[j] == '_') {
+				j += 1
+			}
+			tokens = append(tokens, qlToken{src[i:j], i})
+			i = j
+		default:
+			return nil, fmt.Errorf(
+				"Query error at offset %d: invalid character '%c'",
+				i,
+				r,
+			)
+		}
+	}
+	if len(tokens) == 0 {
+		return nil, errors.New("Query error: empty expression")
+	}
+	return tokens, nil
+}
+
+func (p *qlParser) peek() string {
+	if p.pos < len(p.tokens) {
+		return p.tokens[p.pos].text
+	}
+	return ""
+}
+
+func (p *qlParser) next() string {
+	t := p.peek()
+	p.pos += 1
+	return t
+}
+
+func (p *qlParser) expect(t string) error {
+	if p.peek() != t {
+		return p.errorf("expected '%s'", t)
+	}
+	p.pos += 1
+	return nil
+}
+
+// errorf returns a parsing error located at the current token.
+func (p *qlParser) errorf(format string, a ...any) error {
+	off := len(p.src)
+	if p.pos < len(p.tokens) {
+		off = p.tokens[p.pos].off
+	}
+	return fmt.Errorf(
+		"Query error at offset %d: %s",
+		off,
+		fmt.Sprintf(format, a...),
+	)
+}
+
+func (p *qlParser) expr() (*qlNode, error) {
+	return p.binary("|", p.and)
+}
+
+func (p *qlParser) and() (*qlNode, error) {
+	return p.binary("&", p.unary)
+}
+
+// binary parses a left associative sequence of operands joined by op.
+func (p *qlParser) binary(
+	op string,
+	operand func() (*qlNode, error),
+) (*qlNode, error) {
+	n, err := operand()
+	if err != nil {
+		return nil, err
+	}
+	for p.peek() == op {
+		p.pos += 1
+		m, err := operand()
+		if err != nil {
+			return nil, err
+		}
+		n = &qlNode{op: op, args: []*qlNode{n, m}}
+	}
+	return n, nil
+}
+
+func (p *qlParser) unary() (*qlNode, error) {
+	switch t := p.peek(); t {
+	case "!":
+		p.pos += 1
+		n, err := p.unary()
+		if err != nil {
+			return nil, err
+		}
+		return &qlNode{op: "!", args: []*qlNode{n}}, nil
+	case "(":
+		p.pos += 1
+		n, err := p.expr()
+		if err != nil {
+			return nil, err
+		}
+		if err = p.expect(")"); err != nil {
+			return nil, err
+		}
+		return n, nil
+	case "":
+		return nil, p.errorf("unexpected end of expression")
+	default:
+		return p.predicate()
+	}
+}
+
+func (p *qlParser) predicate() (*qlNode, error) {
+	name := p.peek()
+	pred, ok := qlPredicates[name]
+	if !ok {
+		return nil, p.errorf("unknown predicate '%s'", name)
+	}
+	p.pos += 1
+	if err := p.expect("("); err != nil {
+		return nil, err
+	}
+
+	n := &qlNode{op: name}
+	for {
+		if err := p.term(); err != nil {
+			return nil, err
+		}
+		n.terms = append(n.terms, p.next())
+		if p.peek() != "," {
+			break
+		}
+		p.pos += 1
+	}
+	if err := p.expect(")"); err != nil {
+		return nil, err
+	}
+
+	if len(n.terms) != pred.arity {
+		return nil, fmt.Errorf(
+			"Query error: predicate '%s' takes %d terms but got %d",
+			name,
+			pred.arity,
+			len(n.terms),
+		)
+	}
+	return n, nil
+}
+
+// term checks that the current token is a valid term, registering it as a
+// variable if it is a new free variable.
+func (p *qlParser) term() error {
+	t := p.peek()
+	if slices.Contains(p.terms, t) {
+		return nil
+	}
+	if !p.freeVars {
+		return p.errorf(
+			"expected one of the terms %s",
+			strings.Join(p.terms, ", "),
+		)
+	}
+	if t == "" || !unicode.IsLetter(rune(t[0])) {
+		return p.errorf("expected a term")
+	}
+	if !slices.Contains(p.vars, t) {
+		p.vars = append(p.vars, t)
+	}
+	return nil
+}
+
+// compileFormula returns a query generator for the formula described by the
+// query language expression src over the terms x and c.
+func compileFormula(src string) (func(c query.QConst) compute.SVFormula, error) {
+	n, err := parseQL(src, qlVar, qlInstance)
+	if err != nil {
+		return nil, err
+	}
+	return func(c query.QConst) compute.SVFormula {
+		return func(v query.QVar) compute.Encodable {
+			return n.encode(map[string]qlTerm{
+				qlVar:      {isVar: true, v: v},
+				qlInstance: {c: c},
+			})
+		}
+	}, nil
+}
+
+// compileOrder returns query generators for the strict order described by the
+// query language expression src over the terms x, b and c, holding when x
+// precedes b, and for its reverse.
+func compileOrder(src string) (
+	func(c query.QConst) compute.VCOrder,
+	func(c query.QConst) cvOrder,
+	error,
+) {
+	n, err := parseQL(src, qlVar, qlBest, qlInstance)
+	if err != nil {
+		return nil, nil, err
+	}
+	order := func(cp query.QConst) compute.VCOrder {
+		return func(v query.QVar, c query.QConst) compute.Encodable {
+			return n.encode(map[string]qlTerm{
+				qlVar:      {isVar: true, v: v},
+				qlBest:     {c: c},
+				qlInstance: {c: cp},
+			})
+		}
+	}
+	// The reverse order swaps the roles of x and b: the constant precedes
+	// the variable.
+	rev := func(cp query.QConst) cvOrder {
+		return func(c query.QConst, v query.QVar) compute.Encodable {
+			return n.encode(map[string]qlTerm{
+				qlVar:      {c: c},
+				qlBest:     {isVar: true, v: v},
+				qlInstance: {c: cp},
+			})
+		}
+	}
+	return order, rev, nil
+}
+
+// compileDecision returns a generator of the closed formula described by the
+// query language expression src over the instance c and the variables it
+// names, which are existentially quantified, along with those variables in
+// order of appearance.
+func compileDecision(src string) (
+	func(c query.QConst) compute.Encodable,
+	[]query.QVar,
+	error,
+) {
+	p, err := newQLParser(src, []string{qlInstance})
+	if err != nil {
+		return nil, nil, err
+	}
+	p.freeVars = true
+	n, err := p.parse()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	vs := make([]query.QVar, len(p.vars))
+	for i, name := range p.vars {
+		vs[i] = query.QVar(name)
+	}
+
+	return func(c query.QConst) compute.Encodable {
+		env := map[string]qlTerm{qlInstance: {c: c}}
+		for _, v := range vs {
+			env[string(v)] = qlTerm{isVar: true, v: v}
+		}
+		f := n.encode(env)
+		for i := len(vs) - 1; i >= 0; i-- {
+			f = logop.WithVar{I: vs[i], Q: f}
+		}
+		return f
+	}, vs, nil
+}
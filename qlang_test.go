@@ -0,0 +1,398 @@
+package main
+
+import (
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/jtcaraball/goexpdt/query"
+	"github.com/jtcaraball/goexpdt/query/logop"
+)
+
+// qlString returns the fully parenthesized form of the parsed expression n.
+func qlString(n *qlNode) string {
+	switch n.op {
+	case "&", "|":
+		return "(" + qlString(n.args[0]) + " " + n.op + " " +
+			qlString(n.args[1]) + ")"
+	case "!":
+		return "!" + qlString(n.args[0])
+	default:
+		return n.op + "(" + strings.Join(n.terms, ", ") + ")"
+	}
+}
+
+func TestParseQL(t *testing.T) {
+	tests := []struct {
+		name     string
+		src      string
+		expected string
+	}{
+		{
+			name:     "and binds tighter than or",
+			src:      "full(x) | full(c) & full(x)",
+			expected: "(full(x) | (full(c) & full(x)))",
+		},
+		{
+			name:     "and binds tighter than or on the left",
+			src:      "full(x) & full(c) | full(x)",
+			expected: "((full(x) & full(c)) | full(x))",
+		},
+		{
+			name:     "not binds tighter than and",
+			src:      "!full(x) & full(c)",
+			expected: "(!full(x) & full(c))",
+		},
+		{
+			name:     "not binds tighter than or",
+			src:      "full(x) | !full(c)",
+			expected: "(full(x) | !full(c))",
+		},
+		{
+			name:     "left associative",
+			src:      "full(x) & full(c) & lel(x, c)",
+			expected: "((full(x) & full(c)) & lel(x, c))",
+		},
+		{
+			name:     "parentheses",
+			src:      "!(full(x) | full(c)) & (lel(x, c) | lel(c, x))",
+			expected: "(!(full(x) | full(c)) & (lel(x, c) | lel(c, x)))",
+		},
+		{
+			name:     "double negation",
+			src:      "!!full(x)",
+			expected: "!!full(x)",
+		},
+		{
+			name:     "spacing",
+			src:      " leh( x,c ,x )&full(c)",
+			expected: "(leh(x, c, x) & full(c))",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			n, err := parseQL(test.src, qlVar, qlInstance)
+			if err != nil {
+				t.Fatalf("Failed to parse %s: %s", test.src, err.Error())
+			}
+			if qlString(n) != test.expected {
+				t.Errorf(
+					"Wrong parse.\nExpected %s\nbut got  %s",
+					test.expected,
+					qlString(n),
+				)
+			}
+		})
+	}
+}
+
+func TestParseQL_Errors(t *testing.T) {
+	tests := []struct {
+		name     string
+		src      string
+		expected string
+	}{
+		{
+			name:     "empty",
+			src:      "  ",
+			expected: "Query error: empty expression",
+		},
+		{
+			name:     "invalid character",
+			src:      "full(x) $ full(c)",
+			expected: "Query error at offset 8: invalid character '$'",
+		},
+		{
+			name:     "missing terms",
+			src:      "subsumed(x)",
+			expected: "Query error: predicate 'subsumed' takes 2 terms but got 1",
+		},
+		{
+			name:     "extra terms",
+			src:      "full(x, c)",
+			expected: "Query error: predicate 'full' takes 1 terms but got 2",
+		},
+		{
+			name:     "unknown predicate",
+			src:      "full(x) & foo(c)",
+			expected: "Query error at offset 10: unknown predicate 'foo'",
+		},
+		{
+			name:     "unknown term",
+			src:      "full(x) | full(b)",
+			expected: "Query error at offset 15: expected one of the terms x, c",
+		},
+		{
+			name:     "unclosed predicate",
+			src:      "full(x",
+			expected: "Query error at offset 6: expected ')'",
+		},
+		{
+			name:     "unclosed parenthesis",
+			src:      "(full(x) | full(c)",
+			expected: "Query error at offset 18: expected ')'",
+		},
+		{
+			name:     "missing operand",
+			src:      "full(x) &",
+			expected: "Query error at offset 9: unexpected end of expression",
+		},
+		{
+			name:     "missing connective",
+			src:      "full(x) full(c)",
+			expected: "Query error at offset 8: unexpected 'full'",
+		},
+		{
+			name:     "missing predicate",
+			src:      "full(x) & (x)",
+			expected: "Query error at offset 11: unknown predicate 'x'",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := parseQL(test.src, qlVar, qlInstance)
+			if err == nil {
+				t.Fatalf("Expected error parsing %s", test.src)
+			}
+			if err.Error() != test.expected {
+				t.Errorf(
+					"Wrong error.\nExpected %s\nbut got  %s",
+					test.expected,
+					err.Error(),
+				)
+			}
+		})
+	}
+}
+
+func TestCompileOrder(t *testing.T) {
+	// The order holds when x has strictly more bottoms than b, so its
+	// reverse holds when the constant has strictly more bottoms than the
+	// variable.
+	order, rev, err := compileOrder("lel(x, b) & !lel(b, x)")
+	if err != nil {
+		t.Fatalf("Failed to compile order: %s", err.Error())
+	}
+	ctx := query.BasicQContext(thresholdTree)
+	cp := query.AllBotConst(thresholdTree.Dim())
+
+	for _, c1 := range allConsts(thresholdTree.Dim()) {
+		for _, c2 := range allConsts(thresholdTree.Dim()) {
+			expected := c1.BotCount() > c2.BotCount()
+			v := query.QVar("x")
+			if sat := satisfiableWith(
+				t,
+				logop.WithVar{I: v, Q: order(cp)(v, c2)},
+				ctx,
+				v,
+				c1,
+			); sat != expected {
+				t.Errorf(
+					"Expected order of %s and %s to be %t",
+					c1.AsString(),
+					c2.AsString(),
+					expected,
+				)
+			}
+			if sat := satisfiableWith(
+				t,
+				logop.WithVar{I: v, Q: rev(cp)(c1, v)},
+				ctx,
+				v,
+				c2,
+			); sat != expected {
+				t.Errorf(
+					"Expected reverse order of %s and %s to be %t",
+					c1.AsString(),
+					c2.AsString(),
+					expected,
+				)
+			}
+		}
+	}
+}
+
+func TestCompileOrder_Errors(t *testing.T) {
+	if _, _, err := compileOrder("lel(x, y)"); err == nil {
+		t.Errorf("Expected error compiling order over unknown term y")
+	}
+	if _, _, err := compileOrder("lel(x, b) &"); err == nil {
+		t.Errorf("Expected error compiling incomplete order")
+	}
+}
+
+func TestCompileDecision(t *testing.T) {
+	gen, vs, err := compileDecision(
+		"subsumed(y, c) & full(z) | lel(y, w) & !full(c)",
+	)
+	if err != nil {
+		t.Fatalf("Failed to compile decision: %s", err.Error())
+	}
+
+	expected := []query.QVar{"y", "z", "w"}
+	if !slices.Equal(expected, vs) {
+		t.Fatalf(
+			"Variables not equal.\nExpected %v\nbut got  %v",
+			expected,
+			vs,
+		)
+	}
+
+	// Variables are bound from the first one outwards.
+	f := gen(query.AllBotConst(thresholdTree.Dim()))
+	for _, v := range expected {
+		wv, ok := f.(logop.WithVar)
+		if !ok {
+			t.Fatalf("Expected variable %s to be bound", v)
+		}
+		if wv.I != v {
+			t.Fatalf("Expected variable %s to be bound but got %s", v, wv.I)
+		}
+		f = wv.Q
+	}
+	if _, ok := f.(logop.Or); !ok {
+		t.Errorf("Expected the formula below the bound variables")
+	}
+}
+
+func TestCompileDecision_Closed(t *testing.T) {
+	gen, vs, err := compileDecision("full(c)")
+	if err != nil {
+		t.Fatalf("Failed to compile decision: %s", err.Error())
+	}
+	if len(vs) != 0 {
+		t.Fatalf("Expected no variables but got %v", vs)
+	}
+
+	ctx := query.BasicQContext(thresholdTree)
+	for _, c := range allConsts(thresholdTree.Dim()) {
+		ncnf, err := gen(c).Encoding(ctx)
+		if err != nil {
+			t.Fatalf("Failed to encode decision: %s", err.Error())
+		}
+		if ncnf.TriviallyTrue() != c.IsFull() {
+			t.Errorf(
+				"Expected full(c) of %s to be %t",
+				c.AsString(),
+				c.IsFull(),
+			)
+		}
+	}
+}
+
+func TestCompileDecision_Errors(t *testing.T) {
+	tests := []struct {
+		name     string
+		src      string
+		expected string
+	}{
+		{
+			name:     "invalid term",
+			src:      "full(1)",
+			expected: "Query error at offset 5: invalid character '1'",
+		},
+		{
+			name:     "missing term",
+			src:      "subsumed(y, )",
+			expected: "Query error at offset 12: expected a term",
+		},
+		{
+			name:     "arity",
+			src:      "allpos(y, c)",
+			expected: "Query error: predicate 'allpos' takes 1 terms but got 2",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, _, err := compileDecision(test.src)
+			if err == nil {
+				t.Fatalf("Expected error compiling %s", test.src)
+			}
+			if err.Error() != test.expected {
+				t.Errorf(
+					"Wrong error.\nExpected %s\nbut got  %s",
+					test.expected,
+					err.Error(),
+				)
+			}
+		})
+	}
+}
+
+// The named formulas and orders must match their query language spellings
+// given in the README.
+func TestQLSpellings(t *testing.T) {
+	ctx := query.BasicQContext(thresholdTree)
+	v := query.QVar("x")
+
+	t.Run("sr", func(t *testing.T) {
+		ql, err := compileFormula(
+			"subsumed(x, c) & (allpos(c) & allpos(x) | allneg(c) & allneg(x))",
+		)
+		if err != nil {
+			t.Fatalf("Failed to compile formula: %s", err.Error())
+		}
+		for _, c := range allConsts(thresholdTree.Dim()) {
+			if !c.IsFull() {
+				continue
+			}
+			for _, x := range allConsts(thresholdTree.Dim()) {
+				named := satisfiableWith(t, formulaGens["sr"](c)(v), ctx, v, x)
+				spelled := satisfiableWith(
+					t,
+					logop.WithVar{I: v, Q: ql(c)(v)},
+					ctx,
+					v,
+					x,
+				)
+				if named != spelled {
+					t.Errorf(
+						"SR of %s at %s is %t but its spelling is %t",
+						c.AsString(),
+						x.AsString(),
+						named,
+						spelled,
+					)
+				}
+			}
+		}
+	})
+
+	t.Run("ll", func(t *testing.T) {
+		ql, _, err := compileOrder("lel(x, b) & !lel(b, x)")
+		if err != nil {
+			t.Fatalf("Failed to compile order: %s", err.Error())
+		}
+		cp := query.AllBotConst(thresholdTree.Dim())
+		for _, c := range allConsts(thresholdTree.Dim()) {
+			for _, x := range allConsts(thresholdTree.Dim()) {
+				named := satisfiableWith(
+					t,
+					logop.WithVar{I: v, Q: orderGens["ll"](cp)(v, c)},
+					ctx,
+					v,
+					x,
+				)
+				spelled := satisfiableWith(
+					t,
+					logop.WithVar{I: v, Q: ql(cp)(v, c)},
+					ctx,
+					v,
+					x,
+				)
+				if named != spelled {
+					t.Errorf(
+						"LL of %s and %s is %t but its spelling is %t",
+						x.AsString(),
+						c.AsString(),
+						named,
+						spelled,
+					)
+				}
+			}
+		}
+	})
+}
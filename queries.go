@@ -2,138 +2,264 @@ package main
 
 import (
 	"errors"
+	"fmt"
+	"strings"
 
 	"github.com/jtcaraball/goexpdt/compute"
 	"github.com/jtcaraball/goexpdt/query"
+	"github.com/jtcaraball/goexpdt/query/logop"
 )
 
-type (
-	// closeOptimQueryGenFactory returns a property and strict order generator
-	// based on the query.QContext passed.
-	closeOptimQueryGenFactory func(ctx query.QContext) (
-		compute.SVFormula,
-		compute.VCOrder,
-		error,
-	)
-	// closeOptimQueryGenFactory returns a property and strict order generator
-	// based on the query.QContext and constants cs passed.
-	openOptimQueryGenFactory func(ctx query.QContext, cs ...query.QConst) (
-		compute.SVFormula,
-		compute.VCOrder,
-		error,
-	)
+// openOptimQueryGenFactory returns a property and strict order generator
+// based on the query.QContext and constants cs passed.
+type openOptimQueryGenFactory func(ctx query.QContext, cs ...query.QConst) (
+	compute.SVFormula,
+	compute.VCOrder,
+	error,
+)
+
+// blockFormulaGen returns a formula excluding the instances blocked by the
+// optimum c from the search for further optima.
+type blockFormulaGen func(c query.QConst) compute.SVFormula
+
+// blockGenFactory returns a generator of the formulas blocking the optima
+// found by an enumeration based on the query.QContext and constants cs passed.
+type blockGenFactory func(ctx query.QContext, cs ...query.QConst) (
+	blockFormulaGen,
+	error,
 )
 
 // =========================== //
-//        CLOSE QUERIES        //
+//         OPEN QUERIES        //
 // =========================== //
 
-func SR_LL_C(ctx query.QContext) (
+func DFS_LL_O(ctx query.QContext, cs ...query.QConst) (
 	compute.SVFormula,
 	compute.VCOrder,
 	error,
 ) {
-	c := query.AllBotConst(ctx.Dim())
-	err := randValConst(c, true, ctx)
-	if err != nil {
-		return nil, nil, err
-	}
-	return srFGF(c), llOGF(), nil
+	return dfsFGF(), llOGF(), nil
 }
 
-func SR_SS_C(ctx query.QContext) (
+func CR_LH_O(ctx query.QContext, cs ...query.QConst) (
 	compute.SVFormula,
 	compute.VCOrder,
 	error,
 ) {
-	c := query.AllBotConst(ctx.Dim())
-	err := randValConst(c, true, ctx)
-	if err != nil {
-		return nil, nil, err
+	if len(cs) == 0 {
+		return nil, nil, errors.New("Missing constant in query factory.")
 	}
-	return srFGF(c), ssOGF(), nil
+	return crFGF(cs[0]), lhOGF(cs[0]), nil
 }
 
-func DFS_LL_C(ctx query.QContext) (
+func CA_GH_O(ctx query.QContext, cs ...query.QConst) (
 	compute.SVFormula,
 	compute.VCOrder,
 	error,
 ) {
-	return dfsFGF(), llOGF(), nil
+	if len(cs) == 0 {
+		return nil, nil, errors.New("Missing constant in query factory.")
+	}
+	return caFGF(cs[0]), ghOGF(cs[0]), nil
 }
 
-func CR_LH_C(ctx query.QContext) (
+func SR_LL_O(ctx query.QContext, cs ...query.QConst) (
 	compute.SVFormula,
 	compute.VCOrder,
 	error,
 ) {
-	c := query.AllBotConst(ctx.Dim())
-	err := randValConst(c, true, ctx)
-	if err != nil {
-		return nil, nil, err
+	if len(cs) == 0 {
+		return nil, nil, errors.New("Missing constant in query factory.")
 	}
-	return crFGF(c), lhOGF(c), nil
+	return srFGF(cs[0]), llOGF(), nil
 }
 
-func CA_GH_C(ctx query.QContext) (
+func SR_SS_O(ctx query.QContext, cs ...query.QConst) (
 	compute.SVFormula,
 	compute.VCOrder,
 	error,
 ) {
-	c := query.AllBotConst(ctx.Dim())
-	err := randValConst(c, true, ctx)
-	if err != nil {
-		return nil, nil, err
+	if len(cs) == 0 {
+		return nil, nil, errors.New("Missing constant in query factory.")
 	}
-	return caFGF(c), ghOGF(c), nil
+	return srFGF(cs[0]), ssOGF(), nil
 }
 
-// =========================== //
-//         OPEN QUERIES        //
-// =========================== //
-
-func DFS_LL_O(ctx query.QContext, cs ...query.QConst) (
+func CT_GL_O(ctx query.QContext, cs ...query.QConst) (
 	compute.SVFormula,
 	compute.VCOrder,
 	error,
 ) {
-	return dfsFGF(), llOGF(), nil
+	if len(cs) == 0 {
+		return nil, nil, errors.New("Missing constant in query factory.")
+	}
+	return ctFGF(cs[0]), glOGF(), nil
 }
 
-func CR_LH_O(ctx query.QContext, cs ...query.QConst) (
-	compute.SVFormula,
-	compute.VCOrder,
-	error,
-) {
+// =========================== //
+//       BLOCKING QUERIES      //
+// =========================== //
+
+// NS_B blocks every instance extending a found optimum. When enumerating the
+// optima of the Strict Subsumption order it is equivalent to, and cheaper
+// than, blocking with the reverse of the order.
+func NS_B(ctx query.QContext, cs ...query.QConst) (blockFormulaGen, error) {
+	return nsFGF, nil
+}
+
+func LL_B(ctx query.QContext, cs ...query.QConst) (blockFormulaGen, error) {
+	return optimaBlock(llROGF()), nil
+}
+
+func GL_B(ctx query.QContext, cs ...query.QConst) (blockFormulaGen, error) {
+	return optimaBlock(glROGF()), nil
+}
+
+func LH_B(ctx query.QContext, cs ...query.QConst) (blockFormulaGen, error) {
 	if len(cs) == 0 {
-		return nil, nil, errors.New("Missing constant in query factory.")
+		return nil, errors.New("Missing constant in query factory.")
 	}
-	return crFGF(cs[0]), lhOGF(cs[0]), nil
+	return optimaBlock(lhROGF(cs[0])), nil
 }
 
-func CA_GH_O(ctx query.QContext, cs ...query.QConst) (
-	compute.SVFormula,
-	compute.VCOrder,
-	error,
-) {
+func GH_B(ctx query.QContext, cs ...query.QConst) (blockFormulaGen, error) {
 	if len(cs) == 0 {
-		return nil, nil, errors.New("Missing constant in query factory.")
+		return nil, errors.New("Missing constant in query factory.")
 	}
-	return caFGF(cs[0]), ghOGF(cs[0]), nil
+	return optimaBlock(ghROGF(cs[0])), nil
 }
 
-func SR_LL_O(ctx query.QContext, cs ...query.QConst) (
-	compute.SVFormula,
-	compute.VCOrder,
-	error,
-) {
-	return srFGF(cs[0]), llOGF(), nil
+// optimaBlock returns a generator of formulas blocking a found optimum and
+// every instance it precedes under the reverse order rog. Every optimum found
+// while enumerating with these blocks is incomparable with the previous ones.
+func optimaBlock(rog cvOrder) blockFormulaGen {
+	return func(c query.QConst) compute.SVFormula {
+		return func(v query.QVar) compute.Encodable {
+			return logop.And{
+				Q1: logop.Not{Q: eqFGF(c)(v)},
+				Q2: logop.Not{Q: rog(c, v)},
+			}
+		}
+	}
 }
 
-func SR_SS_O(ctx query.QContext, cs ...query.QConst) (
-	compute.SVFormula,
-	compute.VCOrder,
+// =========================== //
+//       COMPOSED QUERIES      //
+// =========================== //
+
+// formulaGens maps formula names to their generators. Formulas that do not
+// depend on a constant ignore it.
+var formulaGens = map[string]func(c query.QConst) compute.SVFormula{
+	"dfs": func(query.QConst) compute.SVFormula { return dfsFGF() },
+	"sr":  srFGF,
+	"cr":  crFGF,
+	"ca":  caFGF,
+	"ct":  ctFGF,
+}
+
+// orderGens maps strict order names to their generators. Orders that do not
+// depend on a constant ignore it.
+var orderGens = map[string]func(c query.QConst) compute.VCOrder{
+	"ll": func(query.QConst) compute.VCOrder { return llOGF() },
+	"ss": func(query.QConst) compute.VCOrder { return ssOGF() },
+	"gl": func(query.QConst) compute.VCOrder { return glOGF() },
+	"lh": lhOGF,
+	"gh": ghOGF,
+}
+
+// revOrderGens maps strict order names to the generators of their reverse.
+// Orders that do not depend on a constant ignore it.
+var revOrderGens = map[string]func(c query.QConst) cvOrder{
+	"ll": func(query.QConst) cvOrder { return llROGF() },
+	"ss": func(query.QConst) cvOrder { return ssROGF() },
+	"gl": func(query.QConst) cvOrder { return glROGF() },
+	"lh": lhROGF,
+	"gh": ghROGF,
+}
+
+// formulaGen returns the generator of the formula with the given name or, if
+// formula is a query language expression, of the formula it describes.
+func formulaGen(formula string) (func(c query.QConst) compute.SVFormula, error) {
+	if isQLExpr(formula) {
+		return compileFormula(formula)
+	}
+	fgen, ok := formulaGens[formula]
+	if !ok {
+		return nil, fmt.Errorf("Unknown formula '%s'", formula)
+	}
+	return fgen, nil
+}
+
+// orderGen returns the generators of the strict order with the given name and
+// of its reverse or, if order is a query language expression, of the order it
+// describes.
+func orderGen(order string) (
+	func(c query.QConst) compute.VCOrder,
+	func(c query.QConst) cvOrder,
 	error,
 ) {
-	return srFGF(cs[0]), ssOGF(), nil
+	if isQLExpr(order) {
+		return compileOrder(order)
+	}
+	ogen, ok := orderGens[order]
+	if !ok {
+		return nil, nil, fmt.Errorf("Unknown order '%s'", order)
+	}
+	return ogen, revOrderGens[order], nil
+}
+
+// isQLExpr returns true if s is a query language expression rather than the
+// name of a formula or order.
+func isQLExpr(s string) bool {
+	return strings.ContainsAny(s, "()")
+}
+
+// queryLabel returns the display name of a formula or order: names are upper
+// cased and query language expressions are quoted.
+func queryLabel(s string) string {
+	if isQLExpr(s) {
+		return "'" + s + "'"
+	}
+	return strings.ToUpper(s)
+}
+
+// optimaBlockGF returns a blocking query factory for the enumeration of the
+// optima of the order with the given name or query language expression.
+func optimaBlockGF(order string) (blockGenFactory, error) {
+	_, rgen, err := orderGen(order)
+	if err != nil {
+		return nil, err
+	}
+	return func(ctx query.QContext, cs ...query.QConst) (
+		blockFormulaGen,
+		error,
+	) {
+		if len(cs) == 0 {
+			return nil, errors.New("Missing constant in query factory.")
+		}
+		return optimaBlock(rgen(cs[0])), nil
+	}, nil
+}
+
+// openQueryGF returns an open query factory pairing the formula and order
+// with the given names or query language expressions.
+func openQueryGF(formula, order string) (openOptimQueryGenFactory, error) {
+	fgen, err := formulaGen(formula)
+	if err != nil {
+		return nil, err
+	}
+	ogen, _, err := orderGen(order)
+	if err != nil {
+		return nil, err
+	}
+	return func(ctx query.QContext, cs ...query.QConst) (
+		compute.SVFormula,
+		compute.VCOrder,
+		error,
+	) {
+		if len(cs) == 0 {
+			return nil, nil, errors.New("Missing constant in query factory.")
+		}
+		return fgen(cs[0]), ogen(cs[0]), nil
+	}, nil
 }
@@ -0,0 +1,254 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+	"time"
+
+	"goexpdt-experiments/tree"
+
+	"github.com/jtcaraball/goexpdt/query"
+)
+
+// Default query of the REPL.
+const (
+	replFormula = "sr"
+	replOrder   = "ll"
+)
+
+const replHelp = `Commands:
+  <instance>                compute the optimum of the current query for an
+                            instance in the {0,1,_} alphabet or given as comma
+                            separated real values
+  query <formula> <order>   set the current query
+  formula <formula>         set the formula of the current query
+  order <order>             set the order of the current query
+  info                      show the loaded tree and current query
+  help                      show this message
+  quit                      exit the REPL
+Formulas: %s or a query language expression over x and c
+Orders: %s or a query language expression over x, b and c
+`
+
+// repl holds the state of an interactive query session over a loaded tree.
+type repl struct {
+	ctx     query.QContext
+	model   binarizer
+	opts    runOpts
+	formula string
+	order   string
+	queryGF openOptimQueryGenFactory
+	out     io.Writer
+}
+
+// runREPL starts an interactive query session over the tree passed by treeFP
+// reading commands from in and writing results to out until in is exhausted
+// or a quit command is read. Command errors are reported to out without
+// ending the session.
+func runREPL(in io.Reader, out io.Writer, treeFP string, opts runOpts) error {
+	if opts.Positive == allClasses {
+		return errors.New("REPL requires a single positive class")
+	}
+	t, err := tree.Load(treeFP)
+	if err != nil {
+		return err
+	}
+	if opts.Positive != "" {
+		if err = t.SetPositive(opts.Positive); err != nil {
+			return err
+		}
+	}
+
+	r := &repl{ctx: treeQContext(&t), model: &t, opts: opts, out: out}
+	if err = r.setQuery(replFormula, replOrder); err != nil {
+		return err
+	}
+
+	fmt.Fprintf(out, "Loaded %s. Type 'help' for commands.\n", treeFP)
+	r.info()
+
+	scanner := bufio.NewScanner(in)
+	for {
+		fmt.Fprint(out, "> ")
+		if !scanner.Scan() {
+			fmt.Fprintln(out)
+			return scanner.Err()
+		}
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 {
+			continue
+		}
+		if fields[0] == "quit" || fields[0] == "exit" {
+			return nil
+		}
+		if err = r.exec(fields); err != nil {
+			fmt.Fprintf(out, "Error: %s.\n", err.Error())
+		}
+	}
+}
+
+// exec runs the command made of fields.
+func (r *repl) exec(fields []string) error {
+	switch fields[0] {
+	case "help":
+		fmt.Fprintf(
+			r.out,
+			replHelp,
+			strings.Join(sortedKeys(formulaGens), ", "),
+			strings.Join(sortedKeys(orderGens), ", "),
+		)
+		return nil
+	case "info":
+		r.info()
+		return nil
+	case "query":
+		if len(fields) < 3 {
+			return errors.New("Usage: query <formula> <order>")
+		}
+		formula, order, err := splitQuery(fields[1:])
+		if err != nil {
+			return err
+		}
+		if err = r.setQuery(formula, order); err != nil {
+			return err
+		}
+		r.info()
+		return nil
+	case "formula", "order":
+		if len(fields) < 2 {
+			return fmt.Errorf("Usage: %s <%s>", fields[0], fields[0])
+		}
+		formula, order := r.formula, r.order
+		if fields[0] == "formula" {
+			formula = strings.Join(fields[1:], " ")
+		} else {
+			order = strings.Join(fields[1:], " ")
+		}
+		if err := r.setQuery(formula, order); err != nil {
+			return err
+		}
+		r.info()
+		return nil
+	default:
+		// Real valued instances may have spaces after their commas.
+		if len(fields) > 1 && !strings.HasSuffix(fields[0], ",") {
+			return fmt.Errorf("Unknown command '%s'", fields[0])
+		}
+		return r.optim(strings.Join(fields, " "))
+	}
+}
+
+// splitQuery returns the formula and order the arguments of a query command
+// are made of. As query language expressions may span several arguments,
+// they are split at the first point where both the formula and the order are
+// valid.
+func splitQuery(args []string) (string, string, error) {
+	var err error
+	for i := 1; i < len(args); i++ {
+		formula := queryName(strings.Join(args[:i], " "))
+		order := queryName(strings.Join(args[i:], " "))
+		if _, fErr := formulaGen(formula); fErr != nil {
+			if err == nil {
+				err = fErr
+			}
+			continue
+		}
+		if _, _, err = orderGen(order); err == nil {
+			return formula, order, nil
+		}
+	}
+	return "", "", err
+}
+
+// setQuery sets the current query to the formula and order with the given
+// case insensitive names or query language expressions.
+func (r *repl) setQuery(formula, order string) error {
+	formula, order = queryName(formula), queryName(order)
+	qgf, err := openQueryGF(formula, order)
+	if err != nil {
+		return err
+	}
+	r.formula, r.order, r.queryGF = formula, order, qgf
+	return nil
+}
+
+// queryName returns the formula or order s with its name lower cased, query
+// language expressions being left unchanged.
+func queryName(s string) string {
+	if isQLExpr(s) {
+		return s
+	}
+	return strings.ToLower(s)
+}
+
+// info writes the description of the loaded tree and current query.
+func (r *repl) info() {
+	fmt.Fprintf(
+		r.out,
+		"Tree: %d features, %d nodes. Query: %s under %s order.\n",
+		r.ctx.Dim(),
+		len(r.ctx.Nodes()),
+		queryLabel(r.formula),
+		queryLabel(r.order),
+	)
+}
+
+// optim computes and writes the optimum of the current query for the
+// instance represented as s.
+func (r *repl) optim(s string) error {
+	c, err := parseInstance(s, r.model)
+	if err != nil {
+		return fmt.Errorf(
+			"Unknown command or invalid instance '%s': %s",
+			s,
+			strings.TrimSuffix(err.Error(), "."),
+		)
+	}
+
+	defer r.ctx.Reset()
+
+	fg, og, err := r.queryGF(r.ctx, c)
+	if err != nil {
+		return err
+	}
+
+	t := time.Now()
+	res, err := computeOptim(fg, og, query.QVar("x"), r.ctx, r.opts)
+	if err != nil {
+		return fmt.Errorf("Compute error: %s", err.Error())
+	}
+	ts := time.Since(t)
+
+	if c.BotCount() == 0 {
+		val, err := evalConst(c, r.ctx)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(r.out, "  class:   %s\n", targetName(val))
+	}
+	if res.Found {
+		fmt.Fprintf(r.out, "  optimum: %s\n", res.Value.AsString())
+		fmt.Fprintf(r.out, "  #bots:   %d\n", res.Value.BotCount())
+	} else {
+		fmt.Fprintln(r.out, "  optimum: none")
+	}
+	fmt.Fprintf(r.out, "  #calls:  %d\n", res.Calls)
+	fmt.Fprintf(r.out, "  time:    %s\n", ts)
+	fmt.Fprintf(r.out, "  status:  %s\n", res.Status())
+
+	return nil
+}
+
+// sortedKeys returns the keys of m in increasing order.
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	slices.Sort(keys)
+	return keys
+}
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRunREPL(t *testing.T) {
+	// x is split on the thresholds 2 and 4, binarized as x > 2 and x > 4.
+	tBytes := []byte(`{
+		"class_names": ["no", "yes"],
+		"feature_names": ["x", "y"],
+		"feature_types": ["real", "binary"],
+		"nodes": {
+			"0": {"id": 0, "type": "internal", "feature_index": 0,
+				"threshold": 4, "id_left": 1, "id_right": 2},
+			"1": {"id": 1, "type": "internal", "feature_index": 0,
+				"threshold": 2, "id_left": 3, "id_right": 4},
+			"2": {"id": 2, "type": "leaf", "class": "yes"},
+			"3": {"id": 3, "type": "leaf", "class": "no"},
+			"4": {"id": 4, "type": "internal", "feature_index": 1,
+				"id_left": 5, "id_right": 6},
+			"5": {"id": 5, "type": "leaf", "class": "no"},
+			"6": {"id": 6, "type": "leaf", "class": "yes"}
+		}
+	}`)
+	treeFP := filepath.Join(t.TempDir(), "tree.json")
+	if err := os.WriteFile(treeFP, tBytes, 0o644); err != nil {
+		t.Fatalf("Failed to write tree file: %s", err.Error())
+	}
+
+	in := strings.Join([]string{
+		"query CR LH",
+		"query subsumed(x, c) & allpos(x) lel(x, b) & !lel(b, x)",
+		"order gh",
+		"query sr",
+		"query foo ll",
+		"query sr zz",
+		"01_",
+		"1",
+		"bogus command",
+		"quit",
+		"info",
+	}, "\n")
+	var out strings.Builder
+	if err := runREPL(strings.NewReader(in), &out, treeFP, runOpts{}); err != nil {
+		t.Fatalf("Failed to run REPL: %s", err.Error())
+	}
+
+	expected := "Loaded " + treeFP + ". Type 'help' for commands.\n" +
+		"Tree: 3 features, 7 nodes. Query: SR under LL order.\n" +
+		"> Tree: 3 features, 7 nodes. Query: CR under LH order.\n" +
+		"> Tree: 3 features, 7 nodes." +
+		" Query: 'subsumed(x, c) & allpos(x)' under" +
+		" 'lel(x, b) & !lel(b, x)' order.\n" +
+		"> Tree: 3 features, 7 nodes." +
+		" Query: 'subsumed(x, c) & allpos(x)' under GH order.\n" +
+		"> Error: Usage: query <formula> <order>.\n" +
+		"> Error: Unknown formula 'foo'.\n" +
+		"> Error: Unknown order 'zz'.\n" +
+		"> Error: Unknown command or invalid instance '01_': Instance" +
+		" contradicts the order of the thresholds of a feature.\n" +
+		"> Error: Unknown command or invalid instance '1': Invalid string" +
+		" length 1 expected 3.\n" +
+		"> Error: Unknown command 'bogus'.\n" +
+		"> "
+	if out.String() != expected {
+		t.Errorf(
+			"Output not equal.\nExpected %q\nbut got  %q",
+			expected,
+			out.String(),
+		)
+	}
+}
+
+func TestRunREPL_AllClasses(t *testing.T) {
+	err := runREPL(
+		strings.NewReader(""),
+		&strings.Builder{},
+		"tree.json",
+		runOpts{Positive: allClasses},
+	)
+	if err == nil {
+		t.Errorf("Expected error running REPL over every class")
+	}
+}
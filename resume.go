@@ -0,0 +1,123 @@
+package main
+
+import (
+	"bytes"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strconv"
+)
+
+// resumeState holds the progress of a previous run whose output is being
+// resumed.
+type resumeState struct {
+	// header of the previous output.
+	header []string
+	// done maps input file names and classes to the number of results
+	// already written for them.
+	done map[resumeKey]int
+	// path of the previous output and end the offset past its last row
+	// closing a task, to which it is truncated once validated.
+	path string
+	end  int64
+}
+
+// resumeKey identifies the results of an input file for a positive class.
+type resumeKey struct {
+	file  string
+	class string
+}
+
+// loadResumeState reads the partial output file passed by path and returns
+// its progress, ignoring any trailing incomplete row or task. If the output
+// records a seed it replaces opts.Seed so the run continues with the same
+// random instance stream. The output is left untouched until truncate is
+// called on the returned state.
+func loadResumeState(path string, opts *runOpts) (*resumeState, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	// Rows are written whole and newline terminated so anything after the
+	// last newline was interrupted mid write.
+	b = b[:bytes.LastIndexByte(b, '\n')+1]
+
+	cr := csv.NewReader(bytes.NewReader(b))
+	header, err := cr.Read()
+	if err == io.EOF {
+		return nil, errors.New("Resume error: output has no header")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("Resume error: %s", err.Error())
+	}
+
+	fCol := slices.Index(header, "file_name")
+	if fCol < 0 {
+		return nil, errors.New("Resume error: output has no file_name column")
+	}
+	cCol := slices.Index(header, "class")
+	sCol := slices.Index(header, "seed")
+	stCol := slices.Index(header, "status")
+
+	state := &resumeState{
+		header: header,
+		done:   make(map[resumeKey]int),
+		path:   path,
+	}
+	// end is the offset past the last row closing a task. Enumeration tasks
+	// span several rows, only the last of which closes the task.
+	end := cr.InputOffset()
+	for i := 0; ; i++ {
+		row, err := cr.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("Resume error: %s", err.Error())
+		}
+
+		if sCol >= 0 && i == 0 {
+			opts.Seed, err = strconv.ParseInt(row[sCol], 10, 64)
+			if err != nil {
+				return nil, fmt.Errorf(
+					"Resume error: invalid seed '%s'",
+					row[sCol],
+				)
+			}
+		}
+		if stCol >= 0 && row[stCol] == enumFound {
+			continue
+		}
+
+		key := resumeKey{file: row[fCol]}
+		if cCol >= 0 {
+			key.class = row[cCol]
+		}
+		state.done[key] += 1
+		end = cr.InputOffset()
+	}
+
+	state.end = end
+
+	return state, nil
+}
+
+// truncate drops any trailing incomplete row or task from the output being
+// resumed, as the rows of an interrupted task are written again. It must
+// only be called once the output is known to belong to the experiment.
+func (s *resumeState) truncate() error {
+	return os.Truncate(s.path, s.end)
+}
+
+// doneCount returns the number of results already written for input id with
+// positive class class by the run being resumed.
+func (o runOpts) doneCount(id, class string) int {
+	if o.resumed == nil {
+		return 0
+	}
+	return o.resumed.done[resumeKey{file: id, class: class}]
+}
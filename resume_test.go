@@ -0,0 +1,280 @@
+package main
+
+import (
+	"io"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+// writePartialOutput writes the partial output content to a new file and
+// returns its path.
+func writePartialOutput(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "output.csv")
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatalf("Failed to write output file: %s", err.Error())
+	}
+	return path
+}
+
+// outputContent returns the content of the output file at path.
+func outputContent(t *testing.T, path string) string {
+	t.Helper()
+	b, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("Failed to read output file: %s", err.Error())
+	}
+	return string(b)
+}
+
+func TestLoadResumeState(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		// kept is the content left in the output file.
+		kept   string
+		header []string
+		done   map[resumeKey]int
+		seed   int64
+	}{
+		{
+			name: "complete rows",
+			content: "file_name,class,seed,value\n" +
+				"t1.json,a,42,1\n" +
+				"t1.json,a,42,0\n" +
+				"t1.json,b,42,1\n" +
+				"t2.json,a,42,1\n",
+			kept: "file_name,class,seed,value\n" +
+				"t1.json,a,42,1\n" +
+				"t1.json,a,42,0\n" +
+				"t1.json,b,42,1\n" +
+				"t2.json,a,42,1\n",
+			header: []string{"file_name", "class", "seed", "value"},
+			done: map[resumeKey]int{
+				{file: "t1.json", class: "a"}: 2,
+				{file: "t1.json", class: "b"}: 1,
+				{file: "t2.json", class: "a"}: 1,
+			},
+			seed: 42,
+		},
+		{
+			name: "incomplete row",
+			content: "file_name,value\n" +
+				"t1.json,1\n" +
+				"t1.json,0\n" +
+				"t2.js",
+			kept: "file_name,value\n" +
+				"t1.json,1\n" +
+				"t1.json,0\n",
+			header: []string{"file_name", "value"},
+			done:   map[resumeKey]int{{file: "t1.json"}: 2},
+			seed:   7,
+		},
+		{
+			name:    "header only",
+			content: "file_name,seed,value\n",
+			kept:    "file_name,seed,value\n",
+			header:  []string{"file_name", "seed", "value"},
+			done:    map[resumeKey]int{},
+			seed:    7,
+		},
+		{
+			name: "interrupted enumeration",
+			content: "file_name,status,value\n" +
+				"t1.json,found,1\n" +
+				"t1.json,found,0\n" +
+				"t1.json,done,\n" +
+				"t1.json,found,1\n" +
+				"t1.json,limit,\n" +
+				"t2.json,found,1\n" +
+				"t2.json,found,0\n",
+			kept: "file_name,status,value\n" +
+				"t1.json,found,1\n" +
+				"t1.json,found,0\n" +
+				"t1.json,done,\n" +
+				"t1.json,found,1\n" +
+				"t1.json,limit,\n",
+			header: []string{"file_name", "status", "value"},
+			done:   map[resumeKey]int{{file: "t1.json"}: 2},
+			seed:   7,
+		},
+		{
+			name: "interrupted enumeration and row",
+			content: "file_name,status,value\n" +
+				"t1.json,found,1\n" +
+				"t1.json,timeout,\n" +
+				"t2.json,found,1\n" +
+				"t2.json,fou",
+			kept: "file_name,status,value\n" +
+				"t1.json,found,1\n" +
+				"t1.json,timeout,\n",
+			header: []string{"file_name", "status", "value"},
+			done:   map[resumeKey]int{{file: "t1.json"}: 1},
+			seed:   7,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			path := writePartialOutput(t, test.content)
+			opts := runOpts{Seed: 7}
+
+			state, err := loadResumeState(path, &opts)
+			if err != nil {
+				t.Fatalf("Failed to load resume state: %s", err.Error())
+			}
+
+			if !slices.Equal(test.header, state.header) {
+				t.Errorf(
+					"Headers not equal.\nExpected %v\nbut got  %v",
+					test.header,
+					state.header,
+				)
+			}
+			if !maps.Equal(test.done, state.done) {
+				t.Errorf(
+					"Progress not equal.\nExpected %v\nbut got  %v",
+					test.done,
+					state.done,
+				)
+			}
+			if opts.Seed != test.seed {
+				t.Errorf("Expected seed %d but got %d", test.seed, opts.Seed)
+			}
+
+			// The output is only truncated once validated.
+			if b := outputContent(t, path); b != test.content {
+				t.Errorf(
+					"Output changed on load.\nExpected %q\nbut got  %q",
+					test.content,
+					b,
+				)
+			}
+			if err = state.truncate(); err != nil {
+				t.Fatalf("Failed to truncate output: %s", err.Error())
+			}
+			if b := outputContent(t, path); b != test.kept {
+				t.Errorf(
+					"Output not equal.\nExpected %q\nbut got  %q",
+					test.kept,
+					b,
+				)
+			}
+		})
+	}
+}
+
+func TestLoadResumeState_Errors(t *testing.T) {
+	tests := []struct {
+		name     string
+		content  string
+		expected string
+	}{
+		{
+			name:     "empty",
+			content:  "",
+			expected: "Resume error: output has no header",
+		},
+		{
+			name:     "incomplete header",
+			content:  "file_name,val",
+			expected: "Resume error: output has no header",
+		},
+		{
+			name:     "no file column",
+			content:  "class,value\na,1\n",
+			expected: "Resume error: output has no file_name column",
+		},
+		{
+			name:     "invalid seed",
+			content:  "file_name,seed\nt1.json,abc\n",
+			expected: "Resume error: invalid seed 'abc'",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			path := writePartialOutput(t, test.content)
+			_, err := loadResumeState(path, &runOpts{})
+			if err == nil {
+				t.Fatalf("Expected error loading %q", test.content)
+			}
+			if b := outputContent(t, path); b != test.content {
+				t.Errorf("Output changed on error to %q", b)
+			}
+			if err.Error() != test.expected {
+				t.Errorf(
+					"Wrong error.\nExpected %s\nbut got  %s",
+					test.expected,
+					err.Error(),
+				)
+			}
+		})
+	}
+}
+
+func TestNewCSVSink_Resumed(t *testing.T) {
+	content := "file_name,value\nt1.json,1\nt2.js"
+	schema := []column{
+		{Header: "file_name", Type: colString},
+		{Header: "value", Type: colInt},
+	}
+
+	t.Run("matching header", func(t *testing.T) {
+		path := writePartialOutput(t, content)
+		opts := runOpts{}
+		state, err := loadResumeState(path, &opts)
+		if err != nil {
+			t.Fatalf("Failed to load resume state: %s", err.Error())
+		}
+		opts.resumed = state
+		if _, err = newCSVSink(io.Discard, opts, schema); err != nil {
+			t.Fatalf("Failed to create sink: %s", err.Error())
+		}
+		expected := "file_name,value\nt1.json,1\n"
+		if b := outputContent(t, path); b != expected {
+			t.Errorf(
+				"Output not equal.\nExpected %q\nbut got  %q",
+				expected,
+				b,
+			)
+		}
+	})
+
+	t.Run("other experiment", func(t *testing.T) {
+		path := writePartialOutput(t, content)
+		opts := runOpts{}
+		state, err := loadResumeState(path, &opts)
+		if err != nil {
+			t.Fatalf("Failed to load resume state: %s", err.Error())
+		}
+		opts.resumed = state
+		_, err = newCSVSink(io.Discard, opts, schema[:1])
+		if err == nil {
+			t.Fatalf("Expected error resuming output of another experiment")
+		}
+		if b := outputContent(t, path); b != content {
+			t.Errorf("Output changed on error to %q", b)
+		}
+	})
+}
+
+func TestDoneCount(t *testing.T) {
+	var opts runOpts
+	if n := opts.doneCount("t1.json", "a"); n != 0 {
+		t.Errorf("Expected no results without resume but got %d", n)
+	}
+
+	opts.resumed = &resumeState{
+		done: map[resumeKey]int{{file: "t1.json", class: "a"}: 3},
+	}
+	if n := opts.doneCount("t1.json", "a"); n != 3 {
+		t.Errorf("Expected 3 results but got %d", n)
+	}
+	if n := opts.doneCount("t1.json", "b"); n != 0 {
+		t.Errorf("Expected no results for another class but got %d", n)
+	}
+}
@@ -0,0 +1,312 @@
+package main
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"strconv"
+
+	"github.com/xitongsys/parquet-go/writer"
+)
+
+// Result output formats.
+const (
+	formatCSV     = "csv"
+	formatJSONL   = "jsonl"
+	formatParquet = "parquet"
+)
+
+// Column value types.
+const (
+	colInt    = "int"
+	colString = "string"
+)
+
+// column describes a field of the results written by a driver.
+type column struct {
+	// Name of the field in typed formats.
+	Name string
+	// Header of the field in csv outputs.
+	Header string
+	// Type of the field values: colInt or colString.
+	Type string
+	// Optional fields may hold nil values.
+	Optional bool
+}
+
+// resultSink receives the result rows of an experiment. Row values follow
+// the sink's schema: int or int64 values for colInt columns, strings for
+// colString columns and nil for missing optional values.
+type resultSink interface {
+	// Write writes a single result row.
+	Write(row []any) error
+	// Close flushes buffered rows and finalizes the output.
+	Close() error
+}
+
+// newSink returns a sink writing rows with the given schema to out in the
+// format of opts.
+func newSink(out io.Writer, opts runOpts, schema []column) (resultSink, error) {
+	switch opts.Format {
+	case formatCSV:
+		return newCSVSink(out, opts, schema)
+	case formatJSONL:
+		return &jsonlSink{out: out, schema: schema}, nil
+	case formatParquet:
+		return newParquetSink(out, schema)
+	default:
+		return nil, fmt.Errorf("Unknown format '%s'", opts.Format)
+	}
+}
+
+// isFormat returns true if f is a supported output format.
+func isFormat(f string) bool {
+	return f == formatCSV || f == formatJSONL || f == formatParquet
+}
+
+// csvSink writes rows as csv records flushing after every row.
+type csvSink struct {
+	w *csv.Writer
+}
+
+// newCSVSink returns a csv sink over out with the schema's header written to
+// it. If the run resumes a previous output the header is not written again
+// but must match the previous one, and the output is only truncated to its
+// last complete task once it does.
+func newCSVSink(out io.Writer, opts runOpts, schema []column) (*csvSink, error) {
+	header := make([]string, len(schema))
+	for i, col := range schema {
+		header[i] = col.Header
+	}
+
+	s := &csvSink{w: csv.NewWriter(out)}
+
+	if opts.resumed != nil {
+		if !slices.Equal(opts.resumed.header, header) {
+			return nil, errors.New(
+				"Resume error: output header does not match experiment",
+			)
+		}
+		return s, opts.resumed.truncate()
+	}
+
+	if err := s.w.Write(header); err != nil {
+		return nil, err
+	}
+	s.w.Flush()
+
+	return s, s.w.Error()
+}
+
+// Write writes row as a csv record. Missing values are written as "-".
+func (s *csvSink) Write(row []any) error {
+	rec := make([]string, len(row))
+	for i, v := range row {
+		switch v := v.(type) {
+		case nil:
+			rec[i] = "-"
+		case string:
+			rec[i] = v
+		case int:
+			rec[i] = strconv.Itoa(v)
+		case int64:
+			rec[i] = strconv.FormatInt(v, 10)
+		default:
+			return fmt.Errorf("Invalid result value %v", v)
+		}
+	}
+	if err := s.w.Write(rec); err != nil {
+		return err
+	}
+	s.w.Flush() // Experiments are long. Save outputs often.
+	return s.w.Error()
+}
+
+// Close flushes the csv writer.
+func (s *csvSink) Close() error {
+	s.w.Flush()
+	return s.w.Error()
+}
+
+// jsonlSink writes rows as json objects, one per line.
+type jsonlSink struct {
+	out    io.Writer
+	schema []column
+}
+
+// Write writes row as a json object keyed by column name.
+func (s *jsonlSink) Write(row []any) error {
+	b, err := rowJSON(s.schema, row)
+	if err != nil {
+		return err
+	}
+	_, err = s.out.Write(append(b, '\n'))
+	return err
+}
+
+// Close does nothing as rows are written as soon as they are received.
+func (s *jsonlSink) Close() error {
+	return nil
+}
+
+// parquetSink writes rows to a parquet file. The output is only readable
+// after the sink is closed.
+type parquetSink struct {
+	pw     *writer.JSONWriter
+	schema []column
+}
+
+// newParquetSink returns a parquet sink over out using the schema to build
+// the file's schema.
+func newParquetSink(out io.Writer, schema []column) (*parquetSink, error) {
+	type field struct {
+		Tag string
+	}
+	pSchema := struct {
+		Tag    string
+		Fields []field
+	}{Tag: "name=parquet_go_root, repetitiontype=REQUIRED"}
+
+	for _, col := range schema {
+		rep := "REQUIRED"
+		if col.Optional {
+			rep = "OPTIONAL"
+		}
+		typ := "type=INT64"
+		if col.Type == colString {
+			typ = "type=BYTE_ARRAY, convertedtype=UTF8"
+		}
+		pSchema.Fields = append(pSchema.Fields, field{
+			Tag: fmt.Sprintf(
+				"name=%s, %s, repetitiontype=%s",
+				col.Name,
+				typ,
+				rep,
+			),
+		})
+	}
+
+	b, err := json.Marshal(pSchema)
+	if err != nil {
+		return nil, err
+	}
+	pw, err := writer.NewJSONWriterFromWriter(string(b), out, 1)
+	if err != nil {
+		return nil, err
+	}
+
+	return &parquetSink{pw: pw, schema: schema}, nil
+}
+
+// Write appends row to the parquet file.
+func (s *parquetSink) Write(row []any) error {
+	b, err := rowJSON(s.schema, row)
+	if err != nil {
+		return err
+	}
+	return s.pw.Write(string(b))
+}
+
+// Close writes the buffered rows and the parquet footer.
+func (s *parquetSink) Close() error {
+	return s.pw.WriteStop()
+}
+
+// rowJSON returns row encoded as a json object keyed by column name, with
+// fields in schema order, checking the values against the schema.
+func rowJSON(schema []column, row []any) ([]byte, error) {
+	if len(row) != len(schema) {
+		return nil, fmt.Errorf(
+			"Invalid result row: expected %d values but got %d",
+			len(schema),
+			len(row),
+		)
+	}
+
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	for i, col := range schema {
+		v := row[i]
+		ok := false
+		switch v.(type) {
+		case nil:
+			ok = col.Optional
+		case int, int64:
+			ok = col.Type == colInt
+		case string:
+			ok = col.Type == colString
+		}
+		if !ok {
+			return nil, fmt.Errorf(
+				"Invalid result value %v for column '%s'",
+				v,
+				col.Name,
+			)
+		}
+
+		k, _ := json.Marshal(col.Name)
+		val, err := json.Marshal(v)
+		if err != nil {
+			return nil, err
+		}
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		buf.Write(k)
+		buf.WriteByte(':')
+		buf.Write(val)
+	}
+	buf.WriteByte('}')
+
+	return buf.Bytes(), nil
+}
+
+// countingSink wraps a sink counting the rows written to it.
+type countingSink struct {
+	resultSink
+	rows int
+}
+
+// Write writes row to the wrapped sink and counts it if successful.
+func (s *countingSink) Write(row []any) error {
+	if err := s.resultSink.Write(row); err != nil {
+		return err
+	}
+	s.rows += 1
+	return nil
+}
+
+// projectSink wraps a sink writing only the values of the columns with
+// indexes in keep.
+type projectSink struct {
+	resultSink
+	keep []int
+}
+
+// Write writes the kept values of row to the wrapped sink.
+func (s projectSink) Write(row []any) error {
+	p := make([]any, len(s.keep))
+	for i, k := range s.keep {
+		p[i] = row[k]
+	}
+	return s.resultSink.Write(p)
+}
+
+// projectSchema returns schema without the columns in drop and the indexes
+// of the columns kept.
+func projectSchema(schema, drop []column) ([]column, []int) {
+	kept := []column{}
+	keep := []int{}
+	for i, col := range schema {
+		if slices.Contains(drop, col) {
+			continue
+		}
+		kept = append(kept, col)
+		keep = append(keep, i)
+	}
+	return kept, keep
+}
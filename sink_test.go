@@ -0,0 +1,199 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+var sinkSchema = []column{
+	{Name: "file_name", Header: "file_name", Type: colString},
+	{Name: "calls", Header: "#calls", Type: colInt},
+	{Name: "instance", Header: "instance", Type: colString, Optional: true},
+}
+
+func TestRowJSON(t *testing.T) {
+	tests := []struct {
+		name     string
+		row      []any
+		expected string
+	}{
+		{
+			name:     "values",
+			row:      []any{"t1.json", 3, "1_0"},
+			expected: `{"file_name":"t1.json","calls":3,"instance":"1_0"}`,
+		},
+		{
+			name: "int64",
+			row:  []any{"t1.json", int64(1) << 40, "1_0"},
+			expected: `{"file_name":"t1.json","calls":1099511627776,` +
+				`"instance":"1_0"}`,
+		},
+		{
+			name:     "missing optional",
+			row:      []any{"t1.json", 3, nil},
+			expected: `{"file_name":"t1.json","calls":3,"instance":null}`,
+		},
+		{
+			name:     "escaped",
+			row:      []any{`a "b".json`, 3, nil},
+			expected: `{"file_name":"a \"b\".json","calls":3,"instance":null}`,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			b, err := rowJSON(sinkSchema, test.row)
+			if err != nil {
+				t.Fatalf("Failed to encode row: %s", err.Error())
+			}
+			if string(b) != test.expected {
+				t.Errorf(
+					"Row not equal.\nExpected %s\nbut got  %s",
+					test.expected,
+					string(b),
+				)
+			}
+		})
+	}
+}
+
+func TestRowJSON_Errors(t *testing.T) {
+	tests := []struct {
+		name     string
+		row      []any
+		expected string
+	}{
+		{
+			name:     "short row",
+			row:      []any{"t1.json", 3},
+			expected: "Invalid result row: expected 3 values but got 2",
+		},
+		{
+			name:     "long row",
+			row:      []any{"t1.json", 3, nil, nil},
+			expected: "Invalid result row: expected 3 values but got 4",
+		},
+		{
+			name:     "missing required",
+			row:      []any{nil, 3, nil},
+			expected: "Invalid result value <nil> for column 'file_name'",
+		},
+		{
+			name:     "string in int column",
+			row:      []any{"t1.json", "3", nil},
+			expected: "Invalid result value 3 for column 'calls'",
+		},
+		{
+			name:     "int in string column",
+			row:      []any{"t1.json", 3, 10},
+			expected: "Invalid result value 10 for column 'instance'",
+		},
+		{
+			name:     "unsupported type",
+			row:      []any{"t1.json", 1.5, nil},
+			expected: "Invalid result value 1.5 for column 'calls'",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := rowJSON(sinkSchema, test.row)
+			if err == nil {
+				t.Fatalf("Expected error encoding %v", test.row)
+			}
+			if err.Error() != test.expected {
+				t.Errorf(
+					"Wrong error.\nExpected %s\nbut got  %s",
+					test.expected,
+					err.Error(),
+				)
+			}
+		})
+	}
+}
+
+func TestCSVSink(t *testing.T) {
+	var out bytes.Buffer
+	s, err := newCSVSink(&out, runOpts{}, sinkSchema)
+	if err != nil {
+		t.Fatalf("Failed to create sink: %s", err.Error())
+	}
+	for _, row := range [][]any{
+		{"t1.json", 3, "1_0"},
+		{"t2.json", int64(4), nil},
+	} {
+		if err = s.Write(row); err != nil {
+			t.Fatalf("Failed to write row: %s", err.Error())
+		}
+	}
+	if err = s.Write([]any{"t3.json", 1.5, nil}); err == nil {
+		t.Errorf("Expected error writing a float value")
+	}
+	if err = s.Close(); err != nil {
+		t.Fatalf("Failed to close sink: %s", err.Error())
+	}
+
+	expected := "file_name,#calls,instance\nt1.json,3,1_0\nt2.json,4,-\n"
+	if out.String() != expected {
+		t.Errorf(
+			"Output not equal.\nExpected %q\nbut got  %q",
+			expected,
+			out.String(),
+		)
+	}
+}
+
+func TestJSONLSink(t *testing.T) {
+	var out bytes.Buffer
+	s, err := newSink(&out, runOpts{Format: formatJSONL}, sinkSchema)
+	if err != nil {
+		t.Fatalf("Failed to create sink: %s", err.Error())
+	}
+	if err = s.Write([]any{"t1.json", 3, nil}); err != nil {
+		t.Fatalf("Failed to write row: %s", err.Error())
+	}
+	if err = s.Write([]any{"t1.json", nil, nil}); err == nil {
+		t.Errorf("Expected error writing a missing required value")
+	}
+	if err = s.Close(); err != nil {
+		t.Fatalf("Failed to close sink: %s", err.Error())
+	}
+
+	expected := `{"file_name":"t1.json","calls":3,"instance":null}` + "\n"
+	if out.String() != expected {
+		t.Errorf(
+			"Output not equal.\nExpected %q\nbut got  %q",
+			expected,
+			out.String(),
+		)
+	}
+}
+
+func TestParquetSink(t *testing.T) {
+	var out bytes.Buffer
+	s, err := newSink(&out, runOpts{Format: formatParquet}, sinkSchema)
+	if err != nil {
+		t.Fatalf("Failed to create sink: %s", err.Error())
+	}
+	for _, row := range [][]any{
+		{"t1.json", 3, "1_0"},
+		{"t2.json", int64(4), nil},
+	} {
+		if err = s.Write(row); err != nil {
+			t.Fatalf("Failed to write row: %s", err.Error())
+		}
+	}
+	if err = s.Write([]any{"t3.json", "4", nil}); err == nil {
+		t.Errorf("Expected error writing a string to an int column")
+	}
+	if err = s.Close(); err != nil {
+		t.Fatalf("Failed to close sink: %s", err.Error())
+	}
+
+	// Parquet files start and end with their magic number.
+	if !strings.HasPrefix(out.String(), "PAR1") ||
+		!strings.HasSuffix(out.String(), "PAR1") {
+		t.Errorf("Expected a parquet file of %d bytes", out.Len())
+	}
+}
@@ -0,0 +1,359 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jtcaraball/goexpdt/compute"
+	"github.com/jtcaraball/goexpdt/query"
+	"github.com/jtcaraball/goexpdt/query/logop"
+)
+
+// modelParser returns the value of variable v from the model output of a SAT
+// solver.
+type modelParser func(
+	out []byte,
+	v query.QVar,
+	ctx query.QContext,
+) (query.QConst, error)
+
+// satSolver describes a SAT solver executable and the conventions it follows
+// when reporting its results.
+type satSolver struct {
+	// Name used to select the solver.
+	Name string
+	// Path of the solver executable.
+	Path string
+	// Args are passed to the solver before the cnf file path.
+	Args []string
+	// SatCode and UnsatCode are the exit codes used by the solver to signal
+	// a satisfiable and unsatisfiable formula respectively.
+	SatCode   int
+	UnsatCode int
+	// ModelFile is true if the solver writes its model to a file passed as
+	// its last argument instead of writing it to stdout.
+	ModelFile bool
+	// Parse extracts a variable's value from the solver's model output.
+	Parse modelParser
+}
+
+// solvers is the registry of supported SAT solvers.
+var solvers = []satSolver{
+	{
+		Name:      "kissat",
+		Path:      "./kissat",
+		SatCode:   10,
+		UnsatCode: 20,
+		Parse:     compute.GetValueFromBytes,
+	},
+	{
+		Name:      "cadical",
+		Path:      "cadical",
+		SatCode:   10,
+		UnsatCode: 20,
+		Parse:     compute.GetValueFromBytes,
+	},
+	{
+		Name:      "minisat",
+		Path:      "minisat",
+		Args:      []string{"-verb=0"},
+		SatCode:   10,
+		UnsatCode: 20,
+		ModelFile: true,
+		Parse:     parseMinisatModel,
+	},
+	{
+		Name:      "glucose",
+		Path:      "glucose",
+		Args:      []string{"-verb=0", "-model"},
+		SatCode:   10,
+		UnsatCode: 20,
+		Parse:     compute.GetValueFromBytes,
+	},
+	{
+		Name:      "cryptominisat",
+		Path:      "cryptominisat5",
+		Args:      []string{"--verb=0"},
+		SatCode:   10,
+		UnsatCode: 20,
+		Parse:     compute.GetValueFromBytes,
+	},
+}
+
+// errTimeout is returned when a solver call exceeds its time budget.
+var errTimeout = errors.New("Solver time budget exceeded")
+
+// defaultSolver is the name of the solver used when none is selected.
+const defaultSolver = "kissat"
+
+// lookupSolver returns the registered solver with the given name. If path is
+// not empty it replaces the solver's default executable path.
+func lookupSolver(name, path string) (satSolver, error) {
+	for _, s := range solvers {
+		if s.Name == name {
+			if path != "" {
+				s.Path = path
+			}
+			return s, nil
+		}
+	}
+	return satSolver{}, fmt.Errorf(
+		"Unknown solver '%s' (available: %v)",
+		name,
+		solverNames(),
+	)
+}
+
+// configure returns s with its arguments replaced by the space separated
+// args and its exit codes replaced by codes, given as "<sat>,<unsat>". Empty
+// values keep the registered ones.
+func (s satSolver) configure(args, codes string) (satSolver, error) {
+	if args != "" {
+		s.Args = strings.Fields(args)
+	}
+	if codes == "" {
+		return s, nil
+	}
+	sat, unsat, ok := strings.Cut(codes, ",")
+	satCode, err1 := strconv.Atoi(strings.TrimSpace(sat))
+	unsatCode, err2 := strconv.Atoi(strings.TrimSpace(unsat))
+	if !ok || err1 != nil || err2 != nil || satCode == unsatCode {
+		return satSolver{}, fmt.Errorf(
+			"Invalid solver exit codes '%s' (expected <sat>,<unsat>)",
+			codes,
+		)
+	}
+	s.SatCode, s.UnsatCode = satCode, unsatCode
+	return s, nil
+}
+
+// solverNames returns the sorted names of the registered solvers.
+func solverNames() []string {
+	names := make([]string, len(solvers))
+	for i, s := range solvers {
+		names[i] = s.Name
+	}
+	sort.Strings(names)
+	return names
+}
+
+// step writes the encoding of f to cnfPath and runs the solver over it.
+// Returns true and the solver's model output if the formula is satisfiable.
+// The solver process is killed and errTimeout returned if cctx is done before
+// the solver finishes.
+func (s satSolver) step(
+	cctx context.Context,
+	f compute.Encodable,
+	ctx query.QContext,
+	cnfPath string,
+) (bool, []byte, error) {
+	if cctx.Err() != nil {
+		return false, nil, errTimeout
+	}
+	if _, err := encodeFile(f, ctx, cnfPath); err != nil {
+		return false, nil, err
+	}
+	return s.solve(cctx, cnfPath)
+}
+
+// cnfSize holds the number of variables and clauses of a cnf encoding.
+type cnfSize struct {
+	Vars    int
+	Clauses int
+}
+
+// encodeFile writes the encoding of f to cnfPath in DIMACS format and returns
+// its size.
+func encodeFile(
+	f compute.Encodable,
+	ctx query.QContext,
+	cnfPath string,
+) (cnfSize, error) {
+	cnf, err := f.Encoding(ctx)
+	if err != nil {
+		return cnfSize{}, err
+	}
+	if err = cnf.ToFile(cnfPath); err != nil {
+		return cnfSize{}, err
+	}
+	sc, cc := cnf.Clauses()
+	return cnfSize{Vars: cnf.TopV(), Clauses: len(sc) + len(cc)}, nil
+}
+
+// solve runs the solver over the cnf file cnfPath. Returns true and the
+// solver's model output if the formula is satisfiable. The solver process is
+// killed and errTimeout returned if cctx is done before the solver finishes.
+func (s satSolver) solve(
+	cctx context.Context,
+	cnfPath string,
+) (bool, []byte, error) {
+	args := append(append([]string{}, s.Args...), cnfPath)
+	modelPath := cnfPath + ".model"
+	if s.ModelFile {
+		args = append(args, modelPath)
+		defer os.Remove(modelPath)
+	}
+
+	var stderr, stdout bytes.Buffer
+	cmd := exec.CommandContext(cctx, s.Path, args...)
+	cmd.Stderr = &stderr
+	cmd.Stdout = &stdout
+
+	err := cmd.Run()
+	if cctx.Err() != nil {
+		return false, nil, errTimeout
+	}
+	exitErr, ok := err.(*exec.ExitError)
+	if !ok {
+		if err != nil {
+			return false, nil, err
+		}
+		return false, nil, errors.New("Solver exit code could not be recovered")
+	}
+
+	switch exitErr.ExitCode() {
+	case s.UnsatCode:
+		return false, nil, nil
+	case s.SatCode:
+		if !s.ModelFile {
+			return true, stdout.Bytes(), nil
+		}
+		out, err := os.ReadFile(modelPath)
+		if err != nil {
+			return false, nil, err
+		}
+		return true, out, nil
+	default:
+		return false, nil, fmt.Errorf(
+			"Solver %s exited with code %d: %s",
+			s.Name,
+			exitErr.ExitCode(),
+			stderr.String(),
+		)
+	}
+}
+
+// optimOutput corresponds to the output of an optimization computation that
+// may have been interrupted by its time budget.
+type optimOutput struct {
+	compute.OptOutput
+	// TimedOut is true if the computation exceeded its time budget. If Found
+	// is true then Value holds the best value found before the interruption.
+	TimedOut bool
+}
+
+// Status returns "timeout" if the computation exceeded its time budget and
+// "ok" otherwise.
+func (o optimOutput) Status() string {
+	if o.TimedOut {
+		return "timeout"
+	}
+	return "ok"
+}
+
+// computeOptim computes the optimal instance that satisfies the formula
+// generated by fg according to the order generated by og using the solver in
+// opts. It follows the same procedure as compute.ComputeOptim but bounds the
+// whole computation by opts.Timeout and every solver call by
+// opts.CallTimeout, a zero value meaning no bound. Exceeding a bound is not
+// an error: the output is marked as timed out instead. The implications
+// between the features of the model in ctx are conjoined to the formula and
+// the order.
+func computeOptim(
+	fg compute.SVFormula,
+	og compute.VCOrder,
+	v query.QVar,
+	ctx query.QContext,
+	opts runOpts,
+) (optimOutput, error) {
+	var (
+		bm  query.QConst
+		out optimOutput
+	)
+
+	fg, og = impliedQuery(fg, og, ctx)
+
+	tmpfp, err := os.CreateTemp("", "tmp.cnf")
+	if err != nil {
+		return optimOutput{}, err
+	}
+	tmpfp.Close()
+	defer os.Remove(tmpfp.Name())
+
+	qctx, cancel := withBudget(context.Background(), opts.Timeout)
+	defer cancel()
+
+	step := func(f compute.Encodable) (bool, []byte, error) {
+		cctx, cancel := withBudget(qctx, opts.CallTimeout)
+		defer cancel()
+		out.Calls += 1
+		return opts.Solver.step(cctx, f, ctx, tmpfp.Name())
+	}
+
+	sat, model, err := step(logop.WithVar{I: v, Q: fg(v)})
+	for err == nil && sat {
+		bm, err = opts.Solver.Parse(model, v, ctx)
+		if err != nil {
+			return optimOutput{}, err
+		}
+		out.Found, out.Value = true, bm
+		ctx.Reset()
+		sat, model, err = step(
+			logop.WithVar{
+				I: v,
+				Q: logop.And{Q1: fg(v), Q2: og(v, bm)},
+			},
+		)
+	}
+
+	if errors.Is(err, errTimeout) {
+		out.TimedOut = true
+		return out, nil
+	}
+	if err != nil {
+		return optimOutput{}, err
+	}
+
+	return out, nil
+}
+
+// withBudget returns a copy of parent that is cancelled after d. A non
+// positive d imposes no bound.
+func withBudget(
+	parent context.Context,
+	d time.Duration,
+) (context.Context, context.CancelFunc) {
+	if d <= 0 {
+		return context.WithCancel(parent)
+	}
+	return context.WithTimeout(parent, d)
+}
+
+// parseMinisatModel returns the value of variable v from a minisat result
+// file, in which the model is written as a single line of literals following
+// the "SAT" line.
+func parseMinisatModel(
+	out []byte,
+	v query.QVar,
+	ctx query.QContext,
+) (query.QConst, error) {
+	var vlines []byte
+	for _, line := range bytes.Split(out, []byte("\n")) {
+		line = bytes.TrimSpace(line)
+		if len(line) == 0 || bytes.Equal(line, []byte("SAT")) {
+			continue
+		}
+		vlines = append(vlines, "v "...)
+		vlines = append(vlines, line...)
+		vlines = append(vlines, '\n')
+	}
+	return compute.GetValueFromBytes(vlines, v, ctx)
+}
@@ -0,0 +1,155 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/jtcaraball/goexpdt/cnf"
+	"github.com/jtcaraball/goexpdt/compute"
+)
+
+// envTestSolver makes the test binary act as the SAT solver returned by
+// testSolver when set.
+const envTestSolver = "GOEXPDT_TEST_SOLVER"
+
+// testSolver returns a solver running the test binary itself as a solver
+// following the kissat conventions, so that experiments can be tested without
+// a SAT solver installed.
+func testSolver(t *testing.T) satSolver {
+	t.Helper()
+	t.Setenv(envTestSolver, "1")
+	return satSolver{
+		Name:      "test",
+		Path:      os.Args[0],
+		Args:      []string{"-test.run=^TestHelperSolver$", "--"},
+		SatCode:   10,
+		UnsatCode: 20,
+		Parse:     compute.GetValueFromBytes,
+	}
+}
+
+// TestHelperSolver is not a test but the solver run by testSolver. It decides
+// the DIMACS file passed as last argument.
+func TestHelperSolver(t *testing.T) {
+	if os.Getenv(envTestSolver) != "1" {
+		return
+	}
+
+	f, err := os.Open(os.Args[len(os.Args)-1])
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err.Error())
+		os.Exit(1)
+	}
+	defer f.Close()
+
+	var (
+		clauses []cnf.Clause
+		clause  cnf.Clause
+	)
+	sc := bufio.NewScanner(f)
+	sc.Buffer(nil, 1<<24)
+	for sc.Scan() {
+		line := sc.Text()
+		if strings.HasPrefix(line, "p") || strings.HasPrefix(line, "c") {
+			continue
+		}
+		for _, field := range strings.Fields(line) {
+			l, err := strconv.Atoi(field)
+			if err != nil {
+				fmt.Fprintln(os.Stderr, err.Error())
+				os.Exit(1)
+			}
+			if l == 0 {
+				clauses = append(clauses, clause)
+				clause = cnf.Clause{}
+				continue
+			}
+			clause = append(clause, l)
+		}
+	}
+
+	model, ok := solveClauses(clauses)
+	if !ok {
+		fmt.Println("s UNSATISFIABLE")
+		os.Exit(20)
+	}
+	lits := []string{"v"}
+	for _, l := range model {
+		lits = append(lits, strconv.Itoa(l))
+	}
+	fmt.Printf("s SATISFIABLE\n%s 0\n", strings.Join(lits, " "))
+	os.Exit(10)
+}
+
+func TestSatSolverConfigure(t *testing.T) {
+	tests := []struct {
+		name      string
+		args      string
+		codes     string
+		expArgs   []string
+		expSat    int
+		expUnsat  int
+		expectErr bool
+	}{
+		{
+			name:     "registered",
+			expArgs:  []string{"-verb=0"},
+			expSat:   10,
+			expUnsat: 20,
+		},
+		{
+			name:     "args",
+			args:     " -verb=1  -rnd-seed=3 ",
+			expArgs:  []string{"-verb=1", "-rnd-seed=3"},
+			expSat:   10,
+			expUnsat: 20,
+		},
+		{
+			name:     "codes",
+			codes:    "0, 1",
+			expArgs:  []string{"-verb=0"},
+			expSat:   0,
+			expUnsat: 1,
+		},
+		{name: "single code", codes: "10", expectErr: true},
+		{name: "invalid code", codes: "sat,20", expectErr: true},
+		{name: "equal codes", codes: "10,10", expectErr: true},
+	}
+
+	registered, err := lookupSolver("minisat", "")
+	if err != nil {
+		t.Fatalf("Failed to look up solver: %s", err.Error())
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			s, err := registered.configure(test.args, test.codes)
+			if test.expectErr {
+				if err == nil {
+					t.Errorf("Expected error configuring codes %s", test.codes)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Failed to configure solver: %s", err.Error())
+			}
+			if !slices.Equal(test.expArgs, s.Args) ||
+				test.expSat != s.SatCode ||
+				test.expUnsat != s.UnsatCode {
+				t.Errorf(
+					"Solver not equal.\nExpected %v %d %d\nbut got  %v %d %d",
+					test.expArgs,
+					test.expSat,
+					test.expUnsat,
+					s.Args,
+					s.SatCode,
+					s.UnsatCode,
+				)
+			}
+		})
+	}
+}
@@ -0,0 +1,275 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Driver kinds, as named by experiment specs and run manifests.
+const (
+	specRandStats  = "rand:stats"
+	specRandVal    = "rand:val"
+	specVal        = "val"
+	specEnumRand   = "enum:rand"
+	specEnumVal    = "enum:val"
+	specDecideRand = "decide:rand"
+	specDecideVal  = "decide:val"
+)
+
+// expSpec is the declarative definition of an experiment as read from a spec
+// file.
+type expSpec struct {
+	// Name of the experiment. Defaults to the spec file name.
+	Name string `json:"name"`
+	// Driver kind: one of "rand:stats", "rand:val", "val", "enum:rand",
+	// "enum:val", "decide:rand" or "decide:val".
+	Driver string `json:"driver"`
+	// Formula name: one of "dfs", "sr", "cr", "ca" or "ct", or a query
+	// language expression over the terms x and c.
+	Formula string `json:"formula"`
+	// Order name: one of "ll", "ss", "gl", "lh" or "gh", or a query language
+	// expression over the terms x, b and c. Not used by decision drivers,
+	// whose formula is a query language expression over the instance c and
+	// any variables.
+	Order string `json:"order"`
+	// Inputs are tree files for random drivers and optimization files for
+	// the "val" driver.
+	Inputs []string `json:"inputs"`
+	// Repetitions is the number of random instances per input. Only used by
+	// random drivers.
+	Repetitions int `json:"repetitions"`
+	// Limit is the maximum number of optima enumerated per instance. Only
+	// used by enumeration drivers.
+	Limit int `json:"limit"`
+	// Seed of the random instance generator. Overrides the run options when
+	// set.
+	Seed *int64 `json:"seed"`
+	// Timeout and CallTimeout are the query and solver call budgets as
+	// duration strings ("90s", "1h"). Override the run options when set.
+	Timeout     string `json:"timeout"`
+	CallTimeout string `json:"call_timeout"`
+	// Positive class of the trees, "all" to run over every class. Overrides
+	// the run options when set.
+	Positive string `json:"positive"`
+	// Target classification of random instances: "positive", "negative" or
+	// "both". Overrides the run options when set.
+	Target string `json:"target"`
+	// Solver name and optional executable path. Override the run options
+	// when set.
+	Solver     string `json:"solver"`
+	SolverPath string `json:"solver_path"`
+	// Format of the results output. Overrides the run options when set.
+	Format string `json:"format"`
+	// TreeStats adds the tree depth and leaf count columns to the results
+	// when set.
+	TreeStats bool `json:"tree_stats"`
+
+	// path of the spec file, recorded in the run manifest.
+	path string
+}
+
+// loadSpec returns the experiment spec encoded as json in the file passed by
+// path.
+func loadSpec(path string) (expSpec, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return expSpec{}, err
+	}
+
+	var spec expSpec
+	dec := json.NewDecoder(bytes.NewReader(b))
+	dec.DisallowUnknownFields()
+	if err = dec.Decode(&spec); err != nil {
+		return expSpec{}, fmt.Errorf("Spec parsing error: %s", err.Error())
+	}
+
+	if spec.Name == "" {
+		spec.Name = strings.TrimSuffix(
+			filepath.Base(path),
+			filepath.Ext(path),
+		)
+	}
+
+	if err = spec.Validate(); err != nil {
+		return expSpec{}, err
+	}
+	spec.path = path
+
+	return spec, nil
+}
+
+// Validate returns an error if the spec is not a valid experiment definition.
+func (s expSpec) Validate() error {
+	if s.Driver != specRandStats &&
+		s.Driver != specRandVal &&
+		s.Driver != specVal &&
+		s.Driver != specEnumRand &&
+		s.Driver != specEnumVal &&
+		!s.isDecision() {
+		return fmt.Errorf("Spec error: invalid driver '%s'", s.Driver)
+	}
+	if s.isDecision() {
+		if _, _, err := compileDecision(s.Formula); err != nil {
+			return fmt.Errorf("Spec error: invalid formula: %s", err.Error())
+		}
+	} else {
+		if _, err := formulaGen(s.Formula); err != nil {
+			return fmt.Errorf("Spec error: invalid formula: %s", err.Error())
+		}
+		if _, _, err := orderGen(s.Order); err != nil {
+			return fmt.Errorf("Spec error: invalid order: %s", err.Error())
+		}
+	}
+	if len(s.Inputs) == 0 {
+		return errors.New("Spec error: must have at least one input")
+	}
+	if s.Driver != specVal &&
+		s.Driver != specEnumVal &&
+		s.Driver != specDecideVal &&
+		s.Repetitions <= 0 {
+		return errors.New("Spec error: repetitions must be positive")
+	}
+	if (s.Driver == specEnumRand || s.Driver == specEnumVal) && s.Limit <= 0 {
+		return errors.New("Spec error: limit must be positive")
+	}
+	if s.Target != "" && !isTarget(s.Target) {
+		return fmt.Errorf("Spec error: invalid target '%s'", s.Target)
+	}
+	if s.Format != "" && !isFormat(s.Format) {
+		return fmt.Errorf("Spec error: invalid format '%s'", s.Format)
+	}
+	for _, d := range []string{s.Timeout, s.CallTimeout} {
+		if d == "" {
+			continue
+		}
+		if _, err := time.ParseDuration(d); err != nil {
+			return fmt.Errorf("Spec error: invalid duration '%s'", d)
+		}
+	}
+	return nil
+}
+
+// isDecision returns true if the spec uses a decision driver.
+func (s expSpec) isDecision() bool {
+	return s.Driver == specDecideRand || s.Driver == specDecideVal
+}
+
+// experiment returns the experiment defined by the spec and the arguments it
+// must be run with. The spec's solver settings are applied to opts, along
+// with the spec file path for the run manifest.
+func (s expSpec) experiment(opts runOpts) (experiment, runOpts, []string, error) {
+	var (
+		d    driver
+		args []string
+	)
+
+	reps := strconv.Itoa(s.Repetitions)
+	limit := strconv.Itoa(s.Limit)
+
+	var qgf openOptimQueryGenFactory
+	if !s.isDecision() {
+		var err error
+		if qgf, err = openQueryGF(s.Formula, s.Order); err != nil {
+			return experiment{}, opts, nil, err
+		}
+	}
+
+	switch s.Driver {
+	case specDecideRand:
+		d = randDecideDriver{}
+		args = append([]string{reps, s.Formula}, s.Inputs...)
+	case specDecideVal:
+		d = decideDriver{}
+		args = append([]string{s.Formula}, s.Inputs...)
+	case specVal:
+		d = compValDriver{qgf}
+		args = s.Inputs
+	case specEnumRand, specEnumVal:
+		bgf, err := optimaBlockGF(s.Order)
+		if err != nil {
+			return experiment{}, opts, nil, err
+		}
+		if s.Driver == specEnumVal {
+			d = enumDriver{qgf, bgf}
+			args = append([]string{limit}, s.Inputs...)
+		} else {
+			d = randEnumDriver{qgf, bgf}
+			args = append([]string{reps, limit}, s.Inputs...)
+		}
+	default:
+		if s.Driver == specRandStats {
+			d = randStatsDriver{qgf}
+		} else {
+			d = randCompValDriver{qgf}
+		}
+		args = append([]string{reps}, s.Inputs...)
+	}
+
+	if s.Solver != "" || s.SolverPath != "" {
+		name, path := s.Solver, s.SolverPath
+		if name == "" {
+			name = opts.Solver.Name
+		}
+		// The run's solver path, arguments and exit codes are kept unless
+		// the spec names another solver, which they do not describe.
+		if name != opts.Solver.Name {
+			solver, err := lookupSolver(name, path)
+			if err != nil {
+				return experiment{}, opts, nil, err
+			}
+			opts.Solver = solver
+		} else if path != "" {
+			opts.Solver.Path = path
+		}
+	}
+
+	opts.spec = s.path
+	if s.Seed != nil {
+		opts.Seed = *s.Seed
+	}
+	if s.Positive != "" {
+		opts.Positive = s.Positive
+	}
+	if s.Target != "" {
+		opts.Target = s.Target
+	}
+	if s.Format != "" {
+		opts.Format = s.Format
+	}
+	if s.TreeStats {
+		opts.TreeStats = true
+	}
+	if s.Timeout != "" {
+		opts.Timeout, _ = time.ParseDuration(s.Timeout)
+	}
+	if s.CallTimeout != "" {
+		opts.CallTimeout, _ = time.ParseDuration(s.CallTimeout)
+	}
+
+	exp := experiment{
+		Name: s.Name,
+		Description: fmt.Sprintf(
+			"Spec experiment - %s under %s order (%s driver).",
+			queryLabel(s.Formula),
+			queryLabel(s.Order),
+			s.Driver,
+		),
+		d: d,
+	}
+	if s.isDecision() {
+		exp.Description = fmt.Sprintf(
+			"Spec experiment - decide '%s' (%s driver).",
+			s.Formula,
+			s.Driver,
+		)
+	}
+
+	return exp, opts, args, nil
+}
@@ -0,0 +1,320 @@
+package main
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strconv"
+	"text/tabwriter"
+)
+
+// summaryKeys are the columns rows are grouped by when summarizing, next to
+// the query of the output they come from.
+var summaryKeys = []string{"file_name", "tree_dim", "tree_nodes"}
+
+// summaryOptKeys are the columns rows are also grouped by when present in the
+// outputs. Rows of outputs without one of them hold "-" as its value.
+var summaryOptKeys = []string{"solver", "class", "target"}
+
+// summaryMetrics are the columns summarized when present in an output.
+var summaryMetrics = []string{"time (ns)", "#bots", "#calls"}
+
+// outputTSRe matches the timestamp suffix of experiment output names.
+var outputTSRe = regexp.MustCompile(`_\d+-\d+-\d+_\d+:\d+:\d+$`)
+
+// summaryGroup holds the values of the rows of the outputs sharing the same
+// key values.
+type summaryGroup struct {
+	key []string
+	// rows is the number of rows in the group and timeouts the number of
+	// those that ran out of budget.
+	rows     int
+	timeouts int
+	// values maps every metric to its values over the group's completed
+	// rows.
+	values map[string][]float64
+}
+
+// metricSummary holds the statistics of a metric over a group.
+type metricSummary struct {
+	Count  int
+	Mean   float64
+	Median float64
+	P90    float64
+	P99    float64
+	Min    float64
+	Max    float64
+}
+
+// summarizeOutputs returns the groups of rows in the csv outputs passed by
+// paths, in order of first appearance, the keys they are grouped by and the
+// metrics found in them. Rows are grouped by query, summaryKeys and those
+// summaryOptKeys present in any of the outputs.
+func summarizeOutputs(
+	paths []string,
+) ([]*summaryGroup, []string, []string, error) {
+	outputs := make([][][]string, len(paths))
+	keys := append([]string{"query"}, summaryKeys...)
+	for i, p := range paths {
+		rows, err := readOutput(p)
+		if err != nil {
+			return nil, nil, nil, err
+		}
+		for _, k := range summaryKeys {
+			if !slices.Contains(rows[0], k) {
+				return nil, nil, nil, fmt.Errorf(
+					"Output %s has no %s column",
+					p,
+					k,
+				)
+			}
+		}
+		outputs[i] = rows
+	}
+	for _, k := range summaryOptKeys {
+		for _, rows := range outputs {
+			if slices.Contains(rows[0], k) {
+				keys = append(keys, k)
+				break
+			}
+		}
+	}
+
+	var (
+		groups  []*summaryGroup
+		metrics []string
+	)
+	lookup := make(map[string]*summaryGroup)
+
+	for i, p := range paths {
+		header, rows := outputs[i][0], outputs[i][1:]
+
+		// The query column is filled from the output's name.
+		kCols := make([]int, len(keys))
+		for j, k := range keys[1:] {
+			kCols[j+1] = slices.Index(header, k)
+		}
+		mCols := make(map[string]int)
+		for _, m := range summaryMetrics {
+			if c := slices.Index(header, m); c >= 0 {
+				mCols[m] = c
+				if !slices.Contains(metrics, m) {
+					metrics = append(metrics, m)
+				}
+			}
+		}
+		sCol := slices.Index(header, "status")
+
+		for _, row := range rows {
+			key := make([]string, len(kCols))
+			key[0] = outputQuery(p)
+			for j, c := range kCols[1:] {
+				key[j+1] = "-"
+				if c >= 0 {
+					key[j+1] = row[c]
+				}
+			}
+			id := fmt.Sprint(key)
+			g, ok := lookup[id]
+			if !ok {
+				g = &summaryGroup{key: key, values: make(map[string][]float64)}
+				lookup[id] = g
+				groups = append(groups, g)
+			}
+
+			g.rows += 1
+			if sCol >= 0 && row[sCol] == "timeout" {
+				g.timeouts += 1
+				continue
+			}
+			for m, c := range mCols {
+				v, err := strconv.ParseFloat(row[c], 64)
+				if err != nil {
+					return nil, nil, nil, fmt.Errorf(
+						"Output %s: invalid %s value '%s'",
+						p,
+						m,
+						row[c],
+					)
+				}
+				g.values[m] = append(g.values[m], v)
+			}
+		}
+	}
+
+	return groups, keys, metrics, nil
+}
+
+// outputQuery returns the name of the query whose results are held in the
+// output file passed by p.
+func outputQuery(p string) string {
+	base := filepath.Base(p)
+	return outputTSRe.ReplaceAllString(
+		base[:len(base)-len(filepath.Ext(base))],
+		"",
+	)
+}
+
+// readOutput returns the rows of the csv output passed by path including its
+// header.
+func readOutput(path string) ([][]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	rows, err := csv.NewReader(f).ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("Output %s: %s", path, err.Error())
+	}
+	if len(rows) == 0 {
+		return nil, fmt.Errorf("Output %s has no header", path)
+	}
+	return rows, nil
+}
+
+// summarize returns the statistics of values. Percentiles are linearly
+// interpolated between closest ranks.
+func summarize(values []float64) metricSummary {
+	if len(values) == 0 {
+		return metricSummary{}
+	}
+
+	vs := slices.Clone(values)
+	slices.Sort(vs)
+
+	sum := 0.0
+	for _, v := range vs {
+		sum += v
+	}
+
+	return metricSummary{
+		Count:  len(vs),
+		Mean:   sum / float64(len(vs)),
+		Median: percentile(vs, 50),
+		P90:    percentile(vs, 90),
+		P99:    percentile(vs, 99),
+		Min:    vs[0],
+		Max:    vs[len(vs)-1],
+	}
+}
+
+// percentile returns the p-th percentile of the sorted non empty values vs.
+func percentile(vs []float64, p float64) float64 {
+	r := p / 100 * float64(len(vs)-1)
+	lo := int(math.Floor(r))
+	hi := int(math.Ceil(r))
+	return vs[lo] + (vs[hi]-vs[lo])*(r-float64(lo))
+}
+
+// summaryStats are the trailing columns of machine readable summaries,
+// holding the statistics of a metric.
+var summaryStats = []string{"mean", "median", "p90", "p99", "min", "max"}
+
+// summaryRecords returns the summary of groups over metrics as csv records,
+// one per group and metric, starting with a header of the keys the groups
+// are grouped by followed by the group sizes and statistics.
+func summaryRecords(
+	groups []*summaryGroup,
+	keys []string,
+	metrics []string,
+) [][]string {
+	header := append(
+		slices.Clone(keys),
+		"rows",
+		"timeouts",
+		"metric",
+		"count",
+	)
+	records := [][]string{append(header, summaryStats...)}
+	for _, g := range groups {
+		for _, m := range metrics {
+			s := summarize(g.values[m])
+			rec := append(
+				slices.Clone(g.key),
+				strconv.Itoa(g.rows),
+				strconv.Itoa(g.timeouts),
+				m,
+				strconv.Itoa(s.Count),
+			)
+			for _, v := range []float64{
+				s.Mean,
+				s.Median,
+				s.P90,
+				s.P99,
+				s.Min,
+				s.Max,
+			} {
+				rec = append(rec, strconv.FormatFloat(v, 'f', -1, 64))
+			}
+			records = append(records, rec)
+		}
+	}
+	return records
+}
+
+// writeSummaryTable writes the summary records as an aligned table to out.
+func writeSummaryTable(out io.Writer, records [][]string) error {
+	firstStat := len(records[0]) - len(summaryStats)
+
+	tw := tabwriter.NewWriter(out, 0, 0, 2, ' ', tabwriter.AlignRight)
+	for i, rec := range records {
+		for j, v := range rec {
+			f, err := strconv.ParseFloat(v, 64)
+			if i > 0 && j >= firstStat && err == nil {
+				v = strconv.FormatFloat(f, 'f', 2, 64)
+			}
+			if j > 0 {
+				fmt.Fprint(tw, "\t")
+			}
+			fmt.Fprint(tw, v)
+		}
+		fmt.Fprintln(tw, "\t")
+	}
+	return tw.Flush()
+}
+
+// writeSummaryFile writes the summary records as csv to the file passed by
+// path.
+func writeSummaryFile(path string, records [][]string) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	w := csv.NewWriter(f)
+	if err = w.WriteAll(records); err != nil {
+		return err
+	}
+	return f.Close()
+}
+
+// runSummarize summarizes the csv outputs passed by paths, writing a table to
+// out and the machine readable summary to the file passed by sPath.
+func runSummarize(out io.Writer, paths []string, sPath string) error {
+	if len(paths) == 0 {
+		return errors.New("Missing outputs to summarize")
+	}
+
+	groups, keys, metrics, err := summarizeOutputs(paths)
+	if err != nil {
+		return err
+	}
+	if len(metrics) == 0 {
+		return errors.New("Outputs have no metrics to summarize")
+	}
+
+	records := summaryRecords(groups, keys, metrics)
+	if err = writeSummaryTable(out, records); err != nil {
+		return err
+	}
+	return writeSummaryFile(sPath, records)
+}
@@ -0,0 +1,210 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+func TestPercentile(t *testing.T) {
+	tests := []struct {
+		name     string
+		vs       []float64
+		p        float64
+		expected float64
+	}{
+		{name: "single value", vs: []float64{3}, p: 90, expected: 3},
+		{name: "minimum", vs: []float64{1, 2, 3, 4}, p: 0, expected: 1},
+		{name: "maximum", vs: []float64{1, 2, 3, 4}, p: 100, expected: 4},
+		{name: "odd median", vs: []float64{1, 2, 10}, p: 50, expected: 2},
+		{name: "even median", vs: []float64{1, 2, 3, 4}, p: 50, expected: 2.5},
+		{
+			name:     "interpolated",
+			vs:       []float64{0, 10, 20, 30, 40, 50, 60, 70, 80, 90, 100},
+			p:        95,
+			expected: 95,
+		},
+		{name: "p90", vs: []float64{1, 2, 3, 4, 5}, p: 90, expected: 4.6},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if v := percentile(test.vs, test.p); v != test.expected {
+				t.Errorf("Expected %g but got %g", test.expected, v)
+			}
+		})
+	}
+}
+
+func TestSummarize(t *testing.T) {
+	expected := metricSummary{
+		Count:  4,
+		Mean:   3.75,
+		Median: 3,
+		P90:    6.8,
+		P99:    7.88,
+		Min:    1,
+		Max:    8,
+	}
+	s := summarize([]float64{8, 1, 4, 2})
+	const eps = 1e-9
+	for _, v := range [][2]float64{
+		{s.Mean, expected.Mean},
+		{s.Median, expected.Median},
+		{s.P90, expected.P90},
+		{s.P99, expected.P99},
+		{s.Min, expected.Min},
+		{s.Max, expected.Max},
+	} {
+		if v[0]-v[1] > eps || v[1]-v[0] > eps {
+			t.Errorf("Summary not equal.\nExpected %v\nbut got  %v", expected, s)
+			break
+		}
+	}
+	if s.Count != expected.Count {
+		t.Errorf("Expected count %d but got %d", expected.Count, s.Count)
+	}
+
+	if s := summarize(nil); s != (metricSummary{}) {
+		t.Errorf("Expected empty summary of no values but got %v", s)
+	}
+}
+
+func TestSummarizeOutputs(t *testing.T) {
+	dir := t.TempDir()
+	outputs := []struct {
+		name    string
+		content string
+	}{
+		{
+			name: "rand-stats-dfs-ll_17-10-2026_10:00:00.csv",
+			content: "solver,file_name,tree_dim,tree_nodes,class,status," +
+				"#bots,#calls,time (ns)\n" +
+				"kissat,t1.json,4,7,a,ok,1,2,10\n" +
+				"kissat,t1.json,4,7,b,ok,2,2,20\n" +
+				"kissat,t1.json,4,7,a,timeout,0,0,0\n" +
+				"kissat,t1.json,4,7,a,ok,3,4,30\n",
+		},
+		{
+			name: "rand-stats-dfs-ll_17-10-2026_11:00:00.csv",
+			content: "solver,file_name,tree_dim,tree_nodes,class,status," +
+				"#bots,#calls,time (ns)\n" +
+				"minisat,t1.json,4,7,a,ok,5,6,50\n" +
+				"kissat,t1.json,4,7,a,ok,5,6,50\n",
+		},
+		{
+			name: "rand-stats-sr-ll_17-10-2026_10:00:00.csv",
+			content: "file_name,tree_dim,tree_nodes,time (ns)\n" +
+				"t1.json,4,7,70\n",
+		},
+	}
+	var paths []string
+	for _, o := range outputs {
+		p := filepath.Join(dir, o.name)
+		if err := os.WriteFile(p, []byte(o.content), 0o644); err != nil {
+			t.Fatalf("Failed to write output file: %s", err.Error())
+		}
+		paths = append(paths, p)
+	}
+
+	groups, keys, metrics, err := summarizeOutputs(paths)
+	if err != nil {
+		t.Fatalf("Failed to summarize outputs: %s", err.Error())
+	}
+
+	expectedKeys := []string{
+		"query",
+		"file_name",
+		"tree_dim",
+		"tree_nodes",
+		"solver",
+		"class",
+	}
+	if !slices.Equal(expectedKeys, keys) {
+		t.Errorf(
+			"Keys not equal.\nExpected %v\nbut got  %v",
+			expectedKeys,
+			keys,
+		)
+	}
+	expectedMetrics := []string{"time (ns)", "#bots", "#calls"}
+	if !slices.Equal(expectedMetrics, metrics) {
+		t.Errorf(
+			"Metrics not equal.\nExpected %v\nbut got  %v",
+			expectedMetrics,
+			metrics,
+		)
+	}
+
+	expected := []struct {
+		key      []string
+		rows     int
+		timeouts int
+		time     []float64
+	}{
+		{
+			key: []string{
+				"rand-stats-dfs-ll", "t1.json", "4", "7", "kissat", "a",
+			},
+			rows:     4,
+			timeouts: 1,
+			time:     []float64{10, 30, 50},
+		},
+		{
+			key: []string{
+				"rand-stats-dfs-ll", "t1.json", "4", "7", "kissat", "b",
+			},
+			rows: 1,
+			time: []float64{20},
+		},
+		{
+			key: []string{
+				"rand-stats-dfs-ll", "t1.json", "4", "7", "minisat", "a",
+			},
+			rows: 1,
+			time: []float64{50},
+		},
+		{
+			key:  []string{"rand-stats-sr-ll", "t1.json", "4", "7", "-", "-"},
+			rows: 1,
+			time: []float64{70},
+		},
+	}
+	if len(groups) != len(expected) {
+		t.Fatalf("Expected %d groups but got %d", len(expected), len(groups))
+	}
+	for i, g := range groups {
+		e := expected[i]
+		if !slices.Equal(e.key, g.key) ||
+			e.rows != g.rows ||
+			e.timeouts != g.timeouts ||
+			!slices.Equal(e.time, g.values["time (ns)"]) {
+			t.Errorf(
+				"Group %d not equal.\nExpected %v %d %d %v\nbut got  %v %d %d %v",
+				i,
+				e.key,
+				e.rows,
+				e.timeouts,
+				e.time,
+				g.key,
+				g.rows,
+				g.timeouts,
+				g.values["time (ns)"],
+			)
+		}
+	}
+}
+
+func TestSummarizeOutputs_Errors(t *testing.T) {
+	p := filepath.Join(t.TempDir(), "output.csv")
+	content := "file_name,tree_dim\nt1.json,4\n"
+	if err := os.WriteFile(p, []byte(content), 0o644); err != nil {
+		t.Fatalf("Failed to write output file: %s", err.Error())
+	}
+	_, _, _, err := summarizeOutputs([]string{p})
+	expected := "Output " + p + " has no tree_nodes column"
+	if err == nil || err.Error() != expected {
+		t.Errorf("Expected error %q but got %v", expected, err)
+	}
+}
@@ -0,0 +1,43 @@
+package main
+
+import (
+	"io"
+
+	"goexpdt-experiments/tree"
+)
+
+// transformOpts are the transformations applied to a tree by runTransform.
+type transformOpts struct {
+	// Depth the tree is truncated to, no truncation if negative.
+	Depth int
+	// Collapse splits whose subtrees share a single class.
+	Collapse bool
+	// Project the tree onto the features it splits on.
+	Project bool
+}
+
+// runTransform writes to out, in the custom tree json schema, the tree
+// derived from the one encoded in the file passed by treeFP by truncating,
+// collapsing and projecting it, in that order, as set in opts.
+func runTransform(out io.Writer, treeFP string, opts transformOpts) error {
+	t, err := tree.Load(treeFP)
+	if err != nil {
+		return err
+	}
+	if opts.Depth >= 0 {
+		if t, err = t.Truncate(opts.Depth); err != nil {
+			return err
+		}
+	}
+	if opts.Collapse {
+		if t, err = t.Collapse(); err != nil {
+			return err
+		}
+	}
+	if opts.Project {
+		if t, err = t.Project(); err != nil {
+			return err
+		}
+	}
+	return t.WriteJSON(out)
+}
@@ -0,0 +1,148 @@
+package tree
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+
+	"github.com/jtcaraball/goexpdt/query"
+)
+
+// defaultThreshold is the split threshold assumed for binary features whose
+// nodes do not declare a single one.
+const defaultThreshold = 0.5
+
+// feature is a Boolean feature of the tree's binarized feature space. Its
+// value is ONE for instances whose value of the original feature orig is
+// greater than threshold and ZERO otherwise.
+type feature struct {
+	orig      int
+	threshold float64
+	real      bool
+}
+
+// buildFeatureSpace sets the tree's binarized feature space from the
+// encoding treeJSON. Binary features are mapped to a single Boolean feature,
+// keeping the feature indexes of trees without real features unchanged, and
+// real features to one Boolean feature per distinct threshold they are split
+// on, sorted by threshold. Binary features split on a single threshold keep
+// it to binarize real valued instances.
+func (t *tree) buildFeatureSpace(treeJSON *treeJSON) {
+	t.origNames = treeJSON.Features
+	t.origReal = make([]bool, len(treeJSON.Features))
+	t.feats = nil
+	for i := range treeJSON.Features {
+		ths := treeJSON.thresholds(i)
+		t.origReal[i] = treeJSON.featType(i) == featReal
+		if !t.origReal[i] {
+			th := defaultThreshold
+			if len(ths) == 1 {
+				th = ths[0]
+			}
+			t.feats = append(t.feats, feature{orig: i, threshold: th})
+			continue
+		}
+		for _, th := range ths {
+			t.feats = append(t.feats, feature{orig: i, threshold: th, real: true})
+		}
+	}
+	t.featCount = len(t.feats)
+}
+
+// featIndex returns the index in the binarized feature space of the split
+// over the original feature orig with threshold th.
+func (t *tree) featIndex(orig int, th *float64) int {
+	for i, f := range t.feats {
+		if f.orig != orig {
+			continue
+		}
+		if !f.real || th == nil || f.threshold == *th {
+			return i
+		}
+	}
+	return -1
+}
+
+// FeatureNames returns the names of the features of the tree's binarized
+// feature space. Features obtained from real features are named after the
+// condition they represent.
+func (t *tree) FeatureNames() []string {
+	names := make([]string, len(t.feats))
+	for i, f := range t.feats {
+		names[i] = t.origNames[f.orig]
+		if f.real {
+			names[i] += " > " + strconv.FormatFloat(f.threshold, 'g', -1, 64)
+		}
+	}
+	return names
+}
+
+// Implications returns the ordering constraints between the features of the
+// tree's binarized feature space as pairs (i, j) meaning that feature i being
+// ONE implies feature j being ONE (and so j being ZERO implies i being ZERO).
+// Only the constraints between consecutive thresholds of the same original
+// feature are returned as the rest follow from them.
+func (t *tree) Implications() [][2]int {
+	imps := [][2]int{}
+	for i := 1; i < len(t.feats); i++ {
+		if t.feats[i].real && t.feats[i-1].real &&
+			t.feats[i].orig == t.feats[i-1].orig {
+			imps = append(imps, [2]int{i, i - 1})
+		}
+	}
+	return imps
+}
+
+// impliedValue returns the value of feature f implied by the values fixed of
+// the features of the tree's binarized feature space. Thresholds of the same
+// real feature are ordered so a feature fixed to ONE implies every lower
+// threshold is ONE and one fixed to ZERO every greater threshold is ZERO.
+func (t *tree) impliedValue(fixed []query.FeatV, f int) query.FeatV {
+	if fixed[f] != query.BOT {
+		return fixed[f]
+	}
+	ff := t.feats[f]
+	if !ff.real {
+		return query.BOT
+	}
+	for i, v := range fixed {
+		g := t.feats[i]
+		if v == query.BOT || !g.real || g.orig != ff.orig {
+			continue
+		}
+		if v == query.ONE && g.threshold >= ff.threshold {
+			return query.ONE
+		}
+		if v == query.ZERO && g.threshold <= ff.threshold {
+			return query.ZERO
+		}
+	}
+	return query.BOT
+}
+
+// BinarizeInstance returns the partial instance of the tree's binarized
+// feature space corresponding to the real valued instance x, which must have
+// a value for every original feature. NaN values are mapped to BOT.
+func (t *tree) BinarizeInstance(x []float64) (query.QConst, error) {
+	if len(x) != len(t.origNames) {
+		return query.QConst{}, fmt.Errorf(
+			"Invalid instance length %d expected %d.",
+			len(x),
+			len(t.origNames),
+		)
+	}
+
+	c := query.AllBotConst(len(t.feats))
+	for i, f := range t.feats {
+		switch v := x[f.orig]; {
+		case math.IsNaN(v):
+			c.Val[i] = query.BOT
+		case v > f.threshold:
+			c.Val[i] = query.ONE
+		default:
+			c.Val[i] = query.ZERO
+		}
+	}
+
+	return c, nil
+}
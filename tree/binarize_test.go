@@ -0,0 +1,251 @@
+package tree
+
+import (
+	"math"
+	"slices"
+	"testing"
+
+	"github.com/jtcaraball/goexpdt/query"
+)
+
+var realTest = struct {
+	tBytes       []byte
+	nodes        []query.Node
+	featNames    []string
+	implications [][2]int
+}{
+	tBytes: []byte(`
+{
+	"class_names": ["no", "yes"],
+	"positive": "yes",
+	"feature_names": ["age", "flag", "income"],
+	"feature_types": ["real", "binary", "real"],
+	"nodes": {
+		"0": {
+		  "id": 0,
+		  "type": "internal",
+		  "feature_index": 0,
+		  "threshold": 50,
+		  "id_left": 1,
+		  "id_right": 2
+		},
+		"1": {
+		  "id": 1,
+		  "type": "internal",
+		  "feature_index": 0,
+		  "threshold": 30,
+		  "id_left": 3,
+		  "id_right": 4
+		},
+		"2": {
+		  "id": 2,
+		  "type": "internal",
+		  "feature_index": 1,
+		  "threshold": 0.5,
+		  "id_left": 5,
+		  "id_right": 6
+		},
+		"3": {
+		  "id": 3,
+		  "type": "leaf",
+		  "class": "no"
+		},
+		"4": {
+		  "id": 4,
+		  "type": "internal",
+		  "feature_index": 2,
+		  "threshold": 1000.5,
+		  "id_left": 7,
+		  "id_right": 8
+		},
+		"5": {
+		  "id": 5,
+		  "type": "leaf",
+		  "class": "yes"
+		},
+		"6": {
+		  "id": 6,
+		  "type": "leaf",
+		  "class": "no"
+		},
+		"7": {
+		  "id": 7,
+		  "type": "leaf",
+		  "class": "no"
+		},
+		"8": {
+		  "id": 8,
+		  "type": "leaf",
+		  "class": "yes"
+		}
+	}
+}
+	`),
+	nodes: []query.Node{
+		{Feat: 1, ZChild: 1, OChild: 2},
+		{Feat: 0, ZChild: 3, OChild: 4},
+		{Feat: 2, ZChild: 5, OChild: 6},
+		{Value: false, ZChild: -1, OChild: -1},
+		{Feat: 3, ZChild: 7, OChild: 8},
+		{Value: true, ZChild: -1, OChild: -1},
+		{Value: false, ZChild: -1, OChild: -1},
+		{Value: false, ZChild: -1, OChild: -1},
+		{Value: true, ZChild: -1, OChild: -1},
+	},
+	featNames:    []string{"age > 30", "age > 50", "flag", "income > 1000.5"},
+	implications: [][2]int{{1, 0}},
+}
+
+func TestLoad_RealFeatures(t *testing.T) {
+	path, err := writeNewTree(t, realTest.tBytes)
+	if err != nil {
+		t.Fatalf("Failed to write tree file: %s", err.Error())
+	}
+	tTree, err := Load(path)
+	if err != nil {
+		t.Fatalf("Failed to load tree: %s", err.Error())
+	}
+	if tTree.Dim() != len(realTest.featNames) {
+		t.Fatalf(
+			"Wrong dimension. Expected %d but got %d",
+			len(realTest.featNames),
+			tTree.Dim(),
+		)
+	}
+	if !slices.Equal(realTest.nodes, tTree.Nodes()) {
+		t.Errorf(
+			"Trees not equal.\nExpected %v\nbut got  %v",
+			realTest.nodes,
+			tTree.Nodes(),
+		)
+	}
+	if !slices.Equal(realTest.featNames, tTree.FeatureNames()) {
+		t.Errorf(
+			"Feature names not equal.\nExpected %v\nbut got  %v",
+			realTest.featNames,
+			tTree.FeatureNames(),
+		)
+	}
+	if !slices.Equal(realTest.implications, tTree.Implications()) {
+		t.Errorf(
+			"Implications not equal.\nExpected %v\nbut got  %v",
+			realTest.implications,
+			tTree.Implications(),
+		)
+	}
+}
+
+func TestBinarizeInstance(t *testing.T) {
+	path, err := writeNewTree(t, realTest.tBytes)
+	if err != nil {
+		t.Fatalf("Failed to write tree file: %s", err.Error())
+	}
+	tTree, err := Load(path)
+	if err != nil {
+		t.Fatalf("Failed to load tree: %s", err.Error())
+	}
+
+	tests := []struct {
+		x   []float64
+		exp string
+	}{
+		{[]float64{20, 0, 500}, "0000"},
+		{[]float64{40, 1, 2000}, "1011"},
+		{[]float64{50, 0, 1000.5}, "1000"},
+		{[]float64{60, 1, math.NaN()}, "111_"},
+	}
+	for _, test := range tests {
+		c, err := tTree.BinarizeInstance(test.x)
+		if err != nil {
+			t.Errorf("Failed to binarize %v: %s", test.x, err.Error())
+			continue
+		}
+		if c.AsString() != test.exp {
+			t.Errorf(
+				"Wrong binarization of %v. Expected %s but got %s",
+				test.x,
+				test.exp,
+				c.AsString(),
+			)
+		}
+	}
+
+	if _, err = tTree.BinarizeInstance([]float64{1, 2}); err == nil {
+		t.Error("Expected error for instance of wrong length")
+	}
+}
+
+func TestLoad_InferredFeatures(t *testing.T) {
+	// Without declared types x is real as it is split on two thresholds, y
+	// is real as its threshold does not split 0 and 1, and z is binary.
+	tBytes := []byte(`{
+		"class_names": ["no", "yes"],
+		"positive": "yes",
+		"feature_names": ["x", "y", "z"],
+		"nodes": {
+			"0": {"id": 0, "type": "internal", "feature_index": 0,
+				"threshold": 7, "id_left": 1, "id_right": 2},
+			"1": {"id": 1, "type": "internal", "feature_index": 0,
+				"threshold": 3.5, "id_left": 3, "id_right": 4},
+			"2": {"id": 2, "type": "internal", "feature_index": 1,
+				"threshold": 10, "id_left": 5, "id_right": 6},
+			"3": {"id": 3, "type": "internal", "feature_index": 2,
+				"threshold": 0.5, "id_left": 7, "id_right": 8},
+			"4": {"id": 4, "type": "leaf", "class": "yes"},
+			"5": {"id": 5, "type": "leaf", "class": "no"},
+			"6": {"id": 6, "type": "leaf", "class": "yes"},
+			"7": {"id": 7, "type": "leaf", "class": "no"},
+			"8": {"id": 8, "type": "leaf", "class": "yes"}
+		}
+	}`)
+	path, err := writeNewTree(t, tBytes)
+	if err != nil {
+		t.Fatalf("Failed to write tree file: %s", err.Error())
+	}
+	tTree, err := Load(path)
+	if err != nil {
+		t.Fatalf("Failed to load tree: %s", err.Error())
+	}
+
+	featNames := []string{"x > 3.5", "x > 7", "y > 10", "z"}
+	if !slices.Equal(featNames, tTree.FeatureNames()) {
+		t.Errorf(
+			"Feature names not equal.\nExpected %v\nbut got  %v",
+			featNames,
+			tTree.FeatureNames(),
+		)
+	}
+	implications := [][2]int{{1, 0}}
+	if !slices.Equal(implications, tTree.Implications()) {
+		t.Errorf(
+			"Implications not equal.\nExpected %v\nbut got  %v",
+			implications,
+			tTree.Implications(),
+		)
+	}
+}
+
+func TestLoad_BinaryThreshold(t *testing.T) {
+	tBytes := []byte(`{
+		"class_names": ["no", "yes"],
+		"positive": "yes",
+		"feature_names": ["x"],
+		"feature_types": ["binary"],
+		"nodes": {
+			"0": {"id": 0, "type": "internal", "feature_index": 0,
+				"threshold": 2, "id_left": 1, "id_right": 2},
+			"1": {"id": 1, "type": "leaf", "class": "no"},
+			"2": {"id": 2, "type": "leaf", "class": "yes"}
+		}
+	}`)
+	path, err := writeNewTree(t, tBytes)
+	if err != nil {
+		t.Fatalf("Failed to write tree file: %s", err.Error())
+	}
+
+	expected := "Tree encoding error: threshold 2 of binary feature split" +
+		" outside [0, 1) (node 0)"
+	if _, err = Load(path); err == nil || err.Error() != expected {
+		t.Errorf("Expected error %q but got %v", expected, err)
+	}
+}
@@ -0,0 +1,624 @@
+package tree
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/jtcaraball/goexpdt/query"
+)
+
+// maxEnsembleNodes bounds the number of nodes of the tree an ensemble is
+// compiled into.
+const maxEnsembleNodes = 1 << 20
+
+// maxEnsembleSteps bounds the number of member nodes visited while compiling
+// an ensemble. Subtrees whose splits lead to the same class are collapsed
+// and do not count towards maxEnsembleNodes, so their visits are bounded
+// separately.
+var maxEnsembleSteps = 1 << 24
+
+// forestJSON is the encoding of a random forest: a list of trees, in any of
+// the single tree json schemas, whose classification is decided by majority
+// vote. The metadata is shared by every tree and fills the fields the trees
+// do not set.
+type forestJSON struct {
+	Estimators   []json.RawMessage `json:"estimators"`
+	ClassNames   []string          `json:"class_names"`
+	Positive     string            `json:"positive"`
+	Features     []string          `json:"feature_names"`
+	FeatureTypes []string          `json:"feature_types"`
+	NFeatures    int               `json:"n_features"`
+}
+
+// boostedJSON is the encoding of a binary boosted trees classifier as dumped
+// by XGBoost: an instance is classified as the second class if the sum of
+// base_score and the leaf scores of every tree is greater than threshold and
+// as the first class otherwise.
+type boostedJSON struct {
+	Trees        []json.RawMessage `json:"trees"`
+	BaseScore    float64           `json:"base_score"`
+	Threshold    float64           `json:"threshold"`
+	ClassNames   []string          `json:"class_names"`
+	Positive     string            `json:"positive"`
+	Features     []string          `json:"feature_names"`
+	FeatureTypes []string          `json:"feature_types"`
+	NFeatures    int               `json:"n_features"`
+}
+
+// xgbNode is a node of an XGBoost json tree dump. Instances go to the yes
+// child when their value is less than the split condition and to the no child
+// otherwise.
+type xgbNode struct {
+	NodeID         int        `json:"nodeid"`
+	Split          string     `json:"split"`
+	SplitCondition *float64   `json:"split_condition"`
+	Yes            int        `json:"yes"`
+	No             int        `json:"no"`
+	Leaf           *float64   `json:"leaf"`
+	Children       []*xgbNode `json:"children"`
+}
+
+// isEnsembleJSON returns true if jsonBytes encodes a tree ensemble instead of
+// a single tree.
+func isEnsembleJSON(jsonBytes []byte) bool {
+	var probe struct {
+		Estimators json.RawMessage `json:"estimators"`
+		Trees      json.RawMessage `json:"trees"`
+	}
+	return json.Unmarshal(jsonBytes, &probe) == nil &&
+		(probe.Estimators != nil || probe.Trees != nil)
+}
+
+// unmarshalEnsembleJSON returns the encoding of the single tree equivalent to
+// the ensemble encoded in jsonBytes.
+func unmarshalEnsembleJSON(jsonBytes []byte) (*treeJSON, error) {
+	var probe struct {
+		Estimators json.RawMessage `json:"estimators"`
+	}
+	if err := json.Unmarshal(jsonBytes, &probe); err != nil {
+		return nil, err
+	}
+	if probe.Estimators != nil {
+		return unmarshalForestJSON(jsonBytes)
+	}
+	return unmarshalBoostedJSON(jsonBytes)
+}
+
+// ensemble holds the member trees of an ensemble over a shared feature space
+// and the rule combining their leafs.
+type ensemble struct {
+	members []*treeJSON
+	// classes, features and featureTypes of the compiled tree.
+	classes      []string
+	positive     string
+	features     []string
+	featureTypes []string
+	// vote is true for majority vote ensembles, whose leafs hold classes,
+	// and false for additive ones, whose leafs hold the scores in scores.
+	vote      bool
+	scores    []map[int]float64
+	baseScore float64
+	threshold float64
+}
+
+func unmarshalForestJSON(jsonBytes []byte) (*treeJSON, error) {
+	fj := &forestJSON{}
+	if err := json.Unmarshal(jsonBytes, fj); err != nil {
+		return nil, err
+	}
+	if len(fj.Estimators) == 0 {
+		return nil, errors.New("Ensemble encoding error: forest has no trees")
+	}
+
+	e := &ensemble{vote: true}
+	for i, raw := range fj.Estimators {
+		raw, err := withForestMetadata(raw, fj)
+		if err != nil {
+			return nil, err
+		}
+		var tj *treeJSON
+		if isSklearnJSON(raw) {
+			tj, err = unmarshalSklearnJSON(raw)
+		} else {
+			tj, err = unmarhsalTree(raw)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("Ensemble tree %d: %s", i, err.Error())
+		}
+		types := make([]string, len(tj.Features))
+		for j := range types {
+			types[j] = tj.featType(j)
+		}
+		if i > 0 && (!slices.Equal(tj.ClassNames, e.classes) ||
+			!slices.Equal(tj.Features, e.features) ||
+			!slices.Equal(types, e.featureTypes)) {
+			return nil, errors.New(
+				"Ensemble encoding error: trees classes, features or feature" +
+					" types differ",
+			)
+		}
+		if i == 0 {
+			e.classes = tj.ClassNames
+			e.positive = tj.Positive
+			e.features = tj.Features
+			e.featureTypes = types
+		}
+		e.members = append(e.members, tj)
+	}
+
+	return e.compile()
+}
+
+// withForestMetadata returns the tree encoding raw with the forest metadata
+// set in the fields it does not define, under the names used by both single
+// tree schemas.
+func withForestMetadata(
+	raw json.RawMessage,
+	fj *forestJSON,
+) (json.RawMessage, error) {
+	fields := make(map[string]json.RawMessage)
+	if err := json.Unmarshal(raw, &fields); err != nil {
+		return nil, err
+	}
+	meta := map[string]any{
+		"class_names":   fj.ClassNames,
+		"classes":       fj.ClassNames,
+		"positive":      fj.Positive,
+		"feature_names": fj.Features,
+		"feature_types": fj.FeatureTypes,
+		"n_features":    fj.NFeatures,
+	}
+	for k, v := range meta {
+		if _, ok := fields[k]; ok {
+			continue
+		}
+		switch v := v.(type) {
+		case []string:
+			if v == nil {
+				continue
+			}
+		case string:
+			if v == "" {
+				continue
+			}
+		case int:
+			if v == 0 {
+				continue
+			}
+		}
+		b, err := json.Marshal(v)
+		if err != nil {
+			return nil, err
+		}
+		fields[k] = b
+	}
+	return json.Marshal(fields)
+}
+
+func unmarshalBoostedJSON(jsonBytes []byte) (*treeJSON, error) {
+	bj := &boostedJSON{}
+	if err := json.Unmarshal(jsonBytes, bj); err != nil {
+		return nil, err
+	}
+	if len(bj.Trees) == 0 {
+		return nil, errors.New("Ensemble encoding error: ensemble has no trees")
+	}
+
+	roots := make([]*xgbNode, len(bj.Trees))
+	for i, raw := range bj.Trees {
+		// Dumps may hold every tree as a json encoded string.
+		var s string
+		if json.Unmarshal(raw, &s) == nil {
+			raw = json.RawMessage(s)
+		}
+		roots[i] = &xgbNode{}
+		if err := json.Unmarshal(raw, roots[i]); err != nil {
+			return nil, fmt.Errorf("Ensemble tree %d: %s", i, err.Error())
+		}
+	}
+
+	e := &ensemble{
+		classes:      bj.ClassNames,
+		positive:     bj.Positive,
+		features:     bj.Features,
+		featureTypes: bj.FeatureTypes,
+		baseScore:    bj.BaseScore,
+		threshold:    bj.Threshold,
+	}
+	if e.classes == nil {
+		e.classes = []string{"0", "1"}
+	}
+	if len(e.classes) != 2 {
+		return nil, errors.New(
+			"Ensemble encoding error: boosted trees must have two class_names",
+		)
+	}
+	if e.features == nil {
+		nf := bj.NFeatures
+		for _, r := range roots {
+			nf = max(nf, r.maxFeature()+1)
+		}
+		e.features = make([]string, nf)
+		for i := range e.features {
+			e.features[i] = "f" + strconv.Itoa(i)
+		}
+	}
+	// Boosted trees split on real valued thresholds.
+	if e.featureTypes == nil {
+		e.featureTypes = make([]string, len(e.features))
+		for i := range e.featureTypes {
+			e.featureTypes[i] = featReal
+		}
+	}
+
+	for i, r := range roots {
+		tj, scores, err := r.treeJSON(e.features)
+		if err != nil {
+			return nil, fmt.Errorf("Ensemble tree %d: %s", i, err.Error())
+		}
+		e.members = append(e.members, tj)
+		e.scores = append(e.scores, scores)
+	}
+
+	return e.compile()
+}
+
+// maxFeature returns the largest feature index of the form f<i> split on in
+// the subtree rooted at n or -1 if there is none.
+func (n *xgbNode) maxFeature() int {
+	m := -1
+	if i, err := strconv.Atoi(strings.TrimPrefix(n.Split, "f")); err == nil {
+		m = i
+	}
+	for _, c := range n.Children {
+		m = max(m, c.maxFeature())
+	}
+	return m
+}
+
+// treeJSON returns the encoding of the tree rooted at n over the given
+// features along with the scores of its leafs by node id.
+func (n *xgbNode) treeJSON(
+	features []string,
+) (*treeJSON, map[int]float64, error) {
+	tj := newTreeJSON()
+	scores := make(map[int]float64)
+
+	toVisit := []*xgbNode{n}
+	for len(toVisit) > 0 {
+		n := toVisit[len(toVisit)-1]
+		toVisit = toVisit[:len(toVisit)-1]
+
+		if _, ok := tj.Nodes[n.NodeID]; ok {
+			return nil, nil, errors.New(
+				"Ensemble encoding error: repeated nodeid",
+			)
+		}
+
+		if n.Leaf != nil {
+			tj.Nodes[n.NodeID] = &nodeJSON{
+				ID:      n.NodeID,
+				Type:    "leaf",
+				FeatIdx: -1,
+				LeftID:  -1,
+				RightID: -1,
+			}
+			scores[n.NodeID] = *n.Leaf
+			continue
+		}
+
+		feat := slices.Index(features, n.Split)
+		if feat < 0 {
+			i, err := strconv.Atoi(strings.TrimPrefix(n.Split, "f"))
+			if err != nil || i < 0 || i >= len(features) {
+				return nil, nil, fmt.Errorf(
+					"Ensemble encoding error: unknown split feature '%s'",
+					n.Split,
+				)
+			}
+			feat = i
+		}
+		if n.SplitCondition == nil {
+			return nil, nil, errors.New(
+				"Ensemble encoding error: missing split_condition",
+			)
+		}
+		// The yes branch (value < condition) is the ZERO child. Features of
+		// the binarized feature space are ONE above their threshold, so the
+		// threshold is the float right below the condition for values equal
+		// to it to be ONE.
+		th := math.Nextafter(*n.SplitCondition, math.Inf(-1))
+		tj.Nodes[n.NodeID] = &nodeJSON{
+			ID:        n.NodeID,
+			Type:      "internal",
+			FeatIdx:   feat,
+			Threshold: &th,
+			LeftID:    n.Yes,
+			RightID:   n.No,
+		}
+		toVisit = append(toVisit, n.Children...)
+	}
+
+	if err := errors.Join(tj.structureProblems()...); err != nil {
+		return nil, nil, err
+	}
+
+	return tj, scores, nil
+}
+
+// compiledNode is a node of the tree an ensemble is compiled into.
+type compiledNode struct {
+	feat      int
+	class     string
+	zeroChild *compiledNode
+	oneChild  *compiledNode
+}
+
+// ensembleCompiler holds the state of the compilation of an ensemble into a
+// single tree.
+type ensembleCompiler struct {
+	e *ensemble
+	// space is the ensemble's binarized feature space and fixed the values
+	// of its features along the current path.
+	space tree
+	fixed []query.FeatV
+	// minRest and maxRest hold the sum of the minimum and maximum leaf
+	// scores of the members from every index onwards.
+	minRest []float64
+	maxRest []float64
+	// nodes is the number of nodes kept in the compiled tree and steps the
+	// number of member nodes visited.
+	nodes int
+	steps int
+}
+
+// compile returns the encoding of a single tree classifying every instance
+// as the ensemble does. The members are unfolded one after another below the
+// leafs of the previous ones, skipping the splits already decided along the
+// path and stopping as soon as the remaining members can not change the
+// outcome.
+func (e *ensemble) compile() (*treeJSON, error) {
+	// The binarized feature space is built from the splits of every member.
+	all := newTreeJSON()
+	all.Features = e.features
+	all.FeatureTypes = e.featureTypes
+	for _, m := range e.members {
+		for _, n := range m.Nodes {
+			if n.Type == "internal" &&
+				all.featType(n.FeatIdx) == featReal &&
+				n.Threshold == nil {
+				return nil, errors.New(
+					"Ensemble encoding error: missing threshold of real" +
+						" feature split",
+				)
+			}
+			all.Nodes[len(all.Nodes)] = n
+		}
+	}
+
+	c := &ensembleCompiler{e: e}
+	c.space.buildFeatureSpace(all)
+	c.fixed = make([]query.FeatV, c.space.featCount)
+
+	c.minRest = make([]float64, len(e.members)+1)
+	c.maxRest = make([]float64, len(e.members)+1)
+	for i := len(e.scores) - 1; i >= 0; i-- {
+		lo, hi := 0.0, 0.0
+		first := true
+		for _, s := range e.scores[i] {
+			if first {
+				lo, hi, first = s, s, false
+			}
+			lo, hi = min(lo, s), max(hi, s)
+		}
+		c.minRest[i] = c.minRest[i+1] + lo
+		c.maxRest[i] = c.maxRest[i+1] + hi
+	}
+
+	root, err := c.walk(0, 0, make([]int, len(e.classes)), e.baseScore)
+	if err != nil {
+		return nil, err
+	}
+
+	tj := newTreeJSON()
+	tj.ClassNames = e.classes
+	tj.Positive = e.positive
+	if tj.Positive == "" {
+		tj.Positive = e.classes[len(e.classes)-1]
+	}
+	tj.Features = e.features
+	tj.FeatureTypes = e.featureTypes
+	c.emit(tj, root)
+
+	if err = tj.Validate(); err != nil {
+		return nil, err
+	}
+	return tj, nil
+}
+
+// walk returns the compiled subtree classifying the instances reaching node
+// id of member m after the previous members added votes or score.
+func (c *ensembleCompiler) walk(
+	m, id int,
+	votes []int,
+	score float64,
+) (*compiledNode, error) {
+	c.steps += 1
+	if c.steps > maxEnsembleSteps {
+		return nil, fmt.Errorf(
+			"Ensemble error: compilation exceeds %d steps",
+			maxEnsembleSteps,
+		)
+	}
+	if class, ok := c.decided(m, votes, score); ok {
+		return c.leaf(class)
+	}
+
+	n := c.e.members[m].Nodes[id]
+	if n == nil {
+		return nil, fmt.Errorf(
+			"Ensemble tree %d: node with id '%d' does not exist",
+			m,
+			id,
+		)
+	}
+	if n.Type == "leaf" {
+		if c.e.vote {
+			votes = slices.Clone(votes)
+			votes[slices.Index(c.e.classes, n.Class)] += 1
+		} else {
+			score += c.e.scores[m][id]
+		}
+		return c.walk(m+1, 0, votes, score)
+	}
+
+	f := c.space.featIndex(n.FeatIdx, n.Threshold)
+	switch c.value(f) {
+	case query.ZERO:
+		return c.walk(m, n.LeftID, votes, score)
+	case query.ONE:
+		return c.walk(m, n.RightID, votes, score)
+	}
+
+	c.fixed[f] = query.ZERO
+	zc, err := c.walk(m, n.LeftID, votes, score)
+	if err != nil {
+		return nil, err
+	}
+	c.fixed[f] = query.ONE
+	oc, err := c.walk(m, n.RightID, votes, score)
+	if err != nil {
+		return nil, err
+	}
+	c.fixed[f] = query.BOT
+
+	// Splits leading to the same class are not needed.
+	if zc.zeroChild == nil && oc.zeroChild == nil && zc.class == oc.class {
+		c.nodes -= 1
+		return zc, nil
+	}
+	if err = c.count(); err != nil {
+		return nil, err
+	}
+	return &compiledNode{feat: f, zeroChild: zc, oneChild: oc}, nil
+}
+
+// decided returns the class of the instances reaching member m after the
+// previous members added votes or score and true if the remaining members
+// can not change it.
+func (c *ensembleCompiler) decided(
+	m int,
+	votes []int,
+	score float64,
+) (string, bool) {
+	rest := len(c.e.members) - m
+
+	if !c.e.vote {
+		switch {
+		case score+c.minRest[m] > c.e.threshold:
+			return c.e.classes[1], true
+		case score+c.maxRest[m] <= c.e.threshold:
+			return c.e.classes[0], true
+		}
+		return "", false
+	}
+
+	// Ties are broken in favour of the first class.
+	lead := argmaxInt(votes)
+	for k, v := range votes {
+		if k == lead {
+			continue
+		}
+		if v+rest > votes[lead] || (v+rest == votes[lead] && k < lead) {
+			return "", false
+		}
+	}
+	return c.e.classes[lead], true
+}
+
+// value returns the value of feature f implied by the features fixed along
+// the current path.
+func (c *ensembleCompiler) value(f int) query.FeatV {
+	return c.space.impliedValue(c.fixed, f)
+}
+
+func (c *ensembleCompiler) leaf(class string) (*compiledNode, error) {
+	if err := c.count(); err != nil {
+		return nil, err
+	}
+	return &compiledNode{feat: -1, class: class}, nil
+}
+
+// count registers a new node, failing if the compiled tree grows beyond
+// maxEnsembleNodes.
+func (c *ensembleCompiler) count() error {
+	c.nodes += 1
+	if c.nodes > maxEnsembleNodes {
+		return fmt.Errorf(
+			"Ensemble error: compiled tree exceeds %d nodes",
+			maxEnsembleNodes,
+		)
+	}
+	return nil
+}
+
+// emit adds the compiled tree rooted at root to tj numbering its nodes from
+// 0 at the root, siblings getting consecutive ids.
+func (c *ensembleCompiler) emit(tj *treeJSON, root *compiledNode) {
+	type elem struct {
+		n  *compiledNode
+		id int
+	}
+	next := 1
+	toVisit := []elem{{root, 0}}
+	for len(toVisit) > 0 {
+		el := toVisit[len(toVisit)-1]
+		toVisit = toVisit[:len(toVisit)-1]
+
+		if el.n.zeroChild == nil {
+			tj.Nodes[el.id] = &nodeJSON{
+				ID:      el.id,
+				Type:    "leaf",
+				Class:   el.n.class,
+				FeatIdx: -1,
+				LeftID:  -1,
+				RightID: -1,
+			}
+			continue
+		}
+
+		f := c.space.feats[el.n.feat]
+		th := f.threshold
+		zid, oid := next, next+1
+		next += 2
+		tj.Nodes[el.id] = &nodeJSON{
+			ID:        el.id,
+			Type:      "internal",
+			FeatIdx:   f.orig,
+			Threshold: &th,
+			LeftID:    zid,
+			RightID:   oid,
+		}
+		toVisit = append(
+			toVisit,
+			elem{el.n.oneChild, oid},
+			elem{el.n.zeroChild, zid},
+		)
+	}
+}
+
+// argmaxInt returns the index of the first maximum value in vs.
+func argmaxInt(vs []int) int {
+	m := 0
+	for i, v := range vs {
+		if v > vs[m] {
+			m = i
+		}
+	}
+	return m
+}
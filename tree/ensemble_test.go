@@ -0,0 +1,204 @@
+package tree
+
+import (
+	"math"
+	"testing"
+
+	"github.com/jtcaraball/goexpdt/query"
+)
+
+// forestTest is a forest of three stumps, one over each feature, in both
+// single tree schemas. It classifies an instance as "b" iff at least two of
+// its features are ONE.
+var forestTest = []byte(`{
+	"class_names": ["a", "b"],
+	"positive": "b",
+	"feature_names": ["x", "y", "z"],
+	"feature_types": ["binary", "binary", "binary"],
+	"estimators": [
+		{
+			"nodes": {
+				"0": {"id": 0, "type": "internal", "feature_index": 0,
+					"id_left": 1, "id_right": 2},
+				"1": {"id": 1, "type": "leaf", "class": "a"},
+				"2": {"id": 2, "type": "leaf", "class": "b"}
+			}
+		},
+		{
+			"nodes": {
+				"0": {"id": 0, "type": "internal", "feature_index": 1,
+					"id_left": 1, "id_right": 2},
+				"1": {"id": 1, "type": "leaf", "class": "a"},
+				"2": {"id": 2, "type": "leaf", "class": "b"}
+			}
+		},
+		{
+			"children_left": [1, -1, -1],
+			"children_right": [2, -1, -1],
+			"feature": [2, -2, -2],
+			"threshold": [0.5, -2, -2],
+			"value": [[2, 2], [2, 0], [0, 2]]
+		}
+	]
+}`)
+
+// boostedTest is a boosted trees classifier over two real features, one of
+// its trees dumped as a json string. It classifies an instance as "1" iff
+// f0 >= 1 and f1 >= 5.
+var boostedTest = []byte(`{
+	"base_score": 0.5,
+	"threshold": 0,
+	"trees": [
+		{"nodeid": 0, "split": "f0", "split_condition": 1, "yes": 1, "no": 2,
+			"children": [
+				{"nodeid": 1, "leaf": -1},
+				{"nodeid": 2, "leaf": 1}
+			]},
+		"{\"nodeid\": 0, \"split\": \"f1\", \"split_condition\": 5, \"yes\": 1, \"no\": 2, \"children\": [{\"nodeid\": 1, \"leaf\": -2}, {\"nodeid\": 2, \"leaf\": 0.5}]}"
+	]
+}`)
+
+// classify returns the class the tree assigns to the full instance c.
+func (t *tree) classify(c query.QConst) string {
+	n := t.root
+	for n.zeroChild != nil {
+		if c.Val[n.feat] == query.ONE {
+			n = n.oneChild
+		} else {
+			n = n.zeroChild
+		}
+	}
+	return n.class
+}
+
+func TestLoad_Forest(t *testing.T) {
+	path, err := writeNewTree(t, forestTest)
+	if err != nil {
+		t.Fatalf("Failed to write tree file: %s", err.Error())
+	}
+	tTree, err := Load(path)
+	if err != nil {
+		t.Fatalf("Failed to load tree: %s", err.Error())
+	}
+	if tTree.Dim() != 3 {
+		t.Fatalf("Wrong dimension. Expected 3 but got %d", tTree.Dim())
+	}
+	if tTree.Positive() != "b" {
+		t.Errorf("Wrong positive. Expected b but got %s", tTree.Positive())
+	}
+
+	for _, s := range []string{
+		"000", "001", "010", "011", "100", "101", "110", "111",
+	} {
+		c := query.AllBotConst(3)
+		ones := 0
+		for i, r := range s {
+			if r == '1' {
+				c.Val[i] = query.ONE
+				ones += 1
+			} else {
+				c.Val[i] = query.ZERO
+			}
+		}
+		exp := "a"
+		if ones >= 2 {
+			exp = "b"
+		}
+		if got := tTree.classify(c); got != exp {
+			t.Errorf("Wrong class of %s. Expected %s but got %s", s, exp, got)
+		}
+	}
+}
+
+func TestLoad_Boosted(t *testing.T) {
+	path, err := writeNewTree(t, boostedTest)
+	if err != nil {
+		t.Fatalf("Failed to write tree file: %s", err.Error())
+	}
+	tTree, err := Load(path)
+	if err != nil {
+		t.Fatalf("Failed to load tree: %s", err.Error())
+	}
+	if tTree.Dim() != 2 {
+		t.Fatalf("Wrong dimension. Expected 2 but got %d", tTree.Dim())
+	}
+
+	tests := []struct {
+		x   []float64
+		exp string
+	}{
+		{[]float64{0, 0}, "0"},
+		{[]float64{2, 0}, "0"},
+		{[]float64{0, 6}, "0"},
+		{[]float64{2, 6}, "1"},
+		// Values equal to a split condition take its no branch.
+		{[]float64{1, 5}, "1"},
+		{[]float64{math.Nextafter(1, 0), 5}, "0"},
+		{[]float64{1, math.Nextafter(5, 0)}, "0"},
+	}
+	for _, test := range tests {
+		c, err := tTree.BinarizeInstance(test.x)
+		if err != nil {
+			t.Fatalf("Failed to binarize %v: %s", test.x, err.Error())
+		}
+		if got := tTree.classify(c); got != test.exp {
+			t.Errorf(
+				"Wrong class of %v. Expected %s but got %s",
+				test.x,
+				test.exp,
+				got,
+			)
+		}
+	}
+}
+
+func TestLoad_EnsembleErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		tBytes []byte
+	}{
+		{"no trees", []byte(`{"estimators": []}`)},
+		{
+			"unknown feature",
+			[]byte(`{"trees": [{"nodeid": 0, "split": "g", "split_condition": 1,
+				"yes": 1, "no": 2, "children": [
+					{"nodeid": 1, "leaf": 1}, {"nodeid": 2, "leaf": 0}
+				]}]}`),
+		},
+		{
+			"missing child",
+			[]byte(`{"trees": [{"nodeid": 0, "split": "f0", "split_condition": 1,
+				"yes": 1, "no": 2, "children": [{"nodeid": 1, "leaf": 1}]}]}`),
+		},
+		{
+			"three classes",
+			[]byte(`{"class_names": ["a", "b", "c"], "trees": [
+				{"nodeid": 0, "leaf": 1}
+			]}`),
+		},
+	}
+	for _, test := range tests {
+		path, err := writeNewTree(t, test.tBytes)
+		if err != nil {
+			t.Fatalf("Failed to write tree file: %s", err.Error())
+		}
+		if _, err = Load(path); err == nil {
+			t.Errorf("Expected error loading ensemble with %s", test.name)
+		}
+	}
+}
+
+func TestLoad_EnsembleSteps(t *testing.T) {
+	path, err := writeNewTree(t, forestTest)
+	if err != nil {
+		t.Fatalf("Failed to write tree file: %s", err.Error())
+	}
+
+	defer func(steps int) { maxEnsembleSteps = steps }(maxEnsembleSteps)
+	maxEnsembleSteps = 5
+
+	expected := "Ensemble error: compilation exceeds 5 steps"
+	if _, err = Load(path); err == nil || err.Error() != expected {
+		t.Errorf("Expected error %q but got %v", expected, err)
+	}
+}
@@ -0,0 +1,192 @@
+package tree
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/jtcaraball/goexpdt/query"
+)
+
+// Colors of the nodes and edges consistent with a highlighted instance.
+const (
+	highlightFill = "#a6cee3"
+	highlightEdge = "#1f78b4"
+)
+
+// WriteDOT writes the tree to w in the Graphviz DOT language. Internal nodes
+// are labeled with the name of the feature they split on and leafs with their
+// class, edges to ZERO and ONE children with 0 and 1 respectively. If c is
+// not nil the nodes consistent with the partial instance c, those reached by
+// some completion of c, are highlighted along with the edges between them.
+func (t *tree) WriteDOT(w io.Writer, c *query.QConst) error {
+	consistent, err := t.consistentNodes(c)
+	if err != nil {
+		return err
+	}
+	names := t.FeatureNames()
+
+	bw := bufio.NewWriter(w)
+	fmt.Fprintln(bw, "digraph tree {")
+	fmt.Fprintln(bw, "\tnode [fontname=\"Helvetica\"];")
+	fmt.Fprintln(bw, "\tedge [fontname=\"Helvetica\"];")
+	t.walk(func(n *node, _ int) {
+		attrs := []string{}
+		if n.zeroChild == nil {
+			attrs = append(attrs, "label="+dotQuote(n.class), "shape=box")
+		} else {
+			attrs = append(attrs, "label="+dotQuote(names[n.feat]))
+		}
+		if consistent[n.id] {
+			attrs = append(
+				attrs,
+				"style=filled",
+				"fillcolor="+dotQuote(highlightFill),
+			)
+		}
+		fmt.Fprintf(bw, "\tn%d [%s];\n", n.id, strings.Join(attrs, ", "))
+		if n.zeroChild == nil {
+			return
+		}
+		for _, e := range []struct {
+			child *node
+			label string
+		}{{n.zeroChild, "0"}, {n.oneChild, "1"}} {
+			eattrs := []string{"label=" + dotQuote(e.label)}
+			if consistent[n.id] && consistent[e.child.id] {
+				eattrs = append(
+					eattrs,
+					"color="+dotQuote(highlightEdge),
+					"penwidth=2",
+				)
+			}
+			fmt.Fprintf(
+				bw,
+				"\tn%d -> n%d [%s];\n",
+				n.id,
+				e.child.id,
+				strings.Join(eattrs, ", "),
+			)
+		}
+	})
+	fmt.Fprintln(bw, "}")
+	return bw.Flush()
+}
+
+// WriteMermaid writes the tree to w as a Mermaid flowchart labeled and
+// highlighted as by WriteDOT.
+func (t *tree) WriteMermaid(w io.Writer, c *query.QConst) error {
+	consistent, err := t.consistentNodes(c)
+	if err != nil {
+		return err
+	}
+	names := t.FeatureNames()
+
+	var (
+		highlighted []string
+		links       []int
+		link        int
+	)
+
+	bw := bufio.NewWriter(w)
+	fmt.Fprintln(bw, "flowchart TD")
+	t.walk(func(n *node, _ int) {
+		if n.zeroChild == nil {
+			fmt.Fprintf(bw, "\tn%d([%s])\n", n.id, mermaidQuote(n.class))
+		} else {
+			fmt.Fprintf(bw, "\tn%d[%s]\n", n.id, mermaidQuote(names[n.feat]))
+		}
+		if consistent[n.id] {
+			highlighted = append(highlighted, "n"+strconv.Itoa(n.id))
+		}
+		if n.zeroChild == nil {
+			return
+		}
+		for _, e := range []struct {
+			child *node
+			label string
+		}{{n.zeroChild, "0"}, {n.oneChild, "1"}} {
+			fmt.Fprintf(bw, "\tn%d -->|%s| n%d\n", n.id, e.label, e.child.id)
+			if consistent[n.id] && consistent[e.child.id] {
+				links = append(links, link)
+			}
+			link += 1
+		}
+	})
+	if len(highlighted) > 0 {
+		fmt.Fprintf(bw, "\tclassDef consistent fill:%s\n", highlightFill)
+		fmt.Fprintf(
+			bw,
+			"\tclass %s consistent\n",
+			strings.Join(highlighted, ","),
+		)
+	}
+	if len(links) > 0 {
+		ls := make([]string, len(links))
+		for i, l := range links {
+			ls[i] = strconv.Itoa(l)
+		}
+		fmt.Fprintf(
+			bw,
+			"\tlinkStyle %s stroke:%s,stroke-width:2px\n",
+			strings.Join(ls, ","),
+			highlightEdge,
+		)
+	}
+	return bw.Flush()
+}
+
+// consistentNodes returns whether every node of the tree, indexed by id, is
+// reached by some completion of the partial instance c. Completions respect
+// the order of the thresholds of real features, so a split implied by the
+// values of c or by the splits above it is only followed one way. No node is
+// if c is nil.
+func (t *tree) consistentNodes(c *query.QConst) ([]bool, error) {
+	if c == nil {
+		return make([]bool, t.nodeCount), nil
+	}
+	if len(c.Val) != t.featCount {
+		return nil, fmt.Errorf(
+			"Invalid instance length %d expected %d.",
+			len(c.Val),
+			t.featCount,
+		)
+	}
+
+	consistent := make([]bool, t.nodeCount)
+	fixed := slices.Clone(c.Val)
+	var visit func(n *node)
+	visit = func(n *node) {
+		consistent[n.id] = true
+		if n.zeroChild == nil {
+			return
+		}
+		switch t.impliedValue(fixed, n.feat) {
+		case query.ZERO:
+			visit(n.zeroChild)
+		case query.ONE:
+			visit(n.oneChild)
+		default:
+			fixed[n.feat] = query.ZERO
+			visit(n.zeroChild)
+			fixed[n.feat] = query.ONE
+			visit(n.oneChild)
+			fixed[n.feat] = query.BOT
+		}
+	}
+	visit(t.root)
+	return consistent, nil
+}
+
+// dotQuote returns s as a DOT quoted string.
+func dotQuote(s string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(s) + `"`
+}
+
+// mermaidQuote returns s as a Mermaid quoted node label.
+func mermaidQuote(s string) string {
+	return `"` + strings.ReplaceAll(s, `"`, "#quot;") + `"`
+}
@@ -0,0 +1,176 @@
+package tree
+
+import (
+	"bytes"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/jtcaraball/goexpdt/query"
+)
+
+func TestExport(t *testing.T) {
+	path, err := writeNewTree(t, forestTest)
+	if err != nil {
+		t.Fatalf("Failed to write tree file: %s", err.Error())
+	}
+	tTree, err := Load(path)
+	if err != nil {
+		t.Fatalf("Failed to load tree: %s", err.Error())
+	}
+
+	// _00 only reaches leafs of class a.
+	c := query.QConst{Val: []query.FeatV{b, z, z}}
+	consistent, err := tTree.consistentNodes(&c)
+	if err != nil {
+		t.Fatalf("Failed to compute consistent nodes: %s", err.Error())
+	}
+	ids := []int{}
+	for id, ok := range consistent {
+		if ok {
+			ids = append(ids, id)
+		}
+	}
+	if expected := []int{0, 1, 2, 3, 7, 9}; !slices.Equal(expected, ids) {
+		t.Errorf(
+			"Consistent nodes not equal.\nExpected %v\nbut got  %v",
+			expected,
+			ids,
+		)
+	}
+
+	tests := []struct {
+		name  string
+		write func(*bytes.Buffer) error
+		lines []string
+	}{
+		{
+			"dot",
+			func(buf *bytes.Buffer) error { return tTree.WriteDOT(buf, &c) },
+			[]string{
+				"digraph tree {",
+				`	n0 [label="x", style=filled, fillcolor="#a6cee3"];`,
+				`	n0 -> n1 [label="0", color="#1f78b4", penwidth=2];`,
+				`	n1 -> n4 [label="1"];`,
+				`	n9 [label="a", shape=box, style=filled, fillcolor="#a6cee3"];`,
+				`	n10 [label="b", shape=box];`,
+			},
+		},
+		{
+			"mermaid",
+			func(buf *bytes.Buffer) error { return tTree.WriteMermaid(buf, &c) },
+			[]string{
+				"flowchart TD",
+				`	n0["x"]`,
+				`	n0 -->|0| n1`,
+				`	n9(["a"])`,
+				"	class n0,n1,n3,n2,n7,n9 consistent",
+				"	linkStyle 0,1,2,6,8 stroke:#1f78b4,stroke-width:2px",
+			},
+		},
+	}
+	for _, test := range tests {
+		var buf bytes.Buffer
+		if err := test.write(&buf); err != nil {
+			t.Fatalf("Failed to write %s: %s", test.name, err.Error())
+		}
+		lines := strings.Split(buf.String(), "\n")
+		for _, l := range test.lines {
+			if !slices.Contains(lines, l) {
+				t.Errorf("Missing %s line %q in\n%s", test.name, l, buf.String())
+			}
+		}
+	}
+
+	short := query.QConst{Val: []query.FeatV{b}}
+	if err := tTree.WriteDOT(&bytes.Buffer{}, &short); err == nil {
+		t.Error("Expected error for instance of wrong length")
+	}
+}
+
+func TestExport_Implications(t *testing.T) {
+	path, err := writeNewTree(t, realTest.tBytes)
+	if err != nil {
+		t.Fatalf("Failed to write tree file: %s", err.Error())
+	}
+	tTree, err := Load(path)
+	if err != nil {
+		t.Fatalf("Failed to load tree: %s", err.Error())
+	}
+
+	tests := []struct {
+		name     string
+		c        []query.FeatV
+		expected []int
+	}{
+		// age <= 30 implies age <= 50 so the root is only followed left.
+		{"given", []query.FeatV{z, b, b, b}, []int{0, 1, 3}},
+		{
+			"undefined",
+			[]query.FeatV{b, b, b, b},
+			[]int{0, 1, 2, 3, 4, 5, 6, 7, 8},
+		},
+	}
+	for _, test := range tests {
+		c := query.QConst{Val: test.c}
+		consistent, err := tTree.consistentNodes(&c)
+		if err != nil {
+			t.Fatalf("Failed to compute consistent nodes: %s", err.Error())
+		}
+		ids := []int{}
+		for id, ok := range consistent {
+			if ok {
+				ids = append(ids, id)
+			}
+		}
+		if !slices.Equal(test.expected, ids) {
+			t.Errorf(
+				"Consistent nodes of %s not equal.\nExpected %v\nbut got  %v",
+				test.name,
+				test.expected,
+				ids,
+			)
+		}
+	}
+}
+
+func TestExport_PathImplications(t *testing.T) {
+	// The split on x > 4 below x <= 2 can only be followed left.
+	path, err := writeNewTree(t, []byte(`{
+		"class_names": ["no", "yes"],
+		"feature_names": ["x"],
+		"feature_types": ["real"],
+		"nodes": {
+			"0": {"id": 0, "type": "internal", "feature_index": 0,
+				"threshold": 2, "id_left": 1, "id_right": 2},
+			"1": {"id": 1, "type": "internal", "feature_index": 0,
+				"threshold": 4, "id_left": 3, "id_right": 4},
+			"2": {"id": 2, "type": "leaf", "class": "yes"},
+			"3": {"id": 3, "type": "leaf", "class": "no"},
+			"4": {"id": 4, "type": "leaf", "class": "yes"}
+		}
+	}`))
+	if err != nil {
+		t.Fatalf("Failed to write tree file: %s", err.Error())
+	}
+	tTree, err := Load(path)
+	if err != nil {
+		t.Fatalf("Failed to load tree: %s", err.Error())
+	}
+
+	c := query.QConst{Val: []query.FeatV{b, b}}
+	consistent, err := tTree.consistentNodes(&c)
+	if err != nil {
+		t.Fatalf("Failed to compute consistent nodes: %s", err.Error())
+	}
+	if expected := []bool{true, true, true, true, false}; !slices.Equal(
+		expected,
+		consistent,
+	) {
+		t.Errorf(
+			"Consistent nodes not equal.\nExpected %v\nbut got  %v",
+			expected,
+			consistent,
+		)
+	}
+}
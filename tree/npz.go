@@ -0,0 +1,224 @@
+package tree
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// npyArray is a numpy array read from a .npy file. Numeric arrays are stored
+// as float64 values and unicode string arrays as strings.
+type npyArray struct {
+	shape []int
+	nums  []float64
+	strs  []string
+}
+
+var (
+	npyMagic  = []byte("\x93NUMPY")
+	descrRe   = regexp.MustCompile(`'descr':\s*'([^']*)'`)
+	fortranRe = regexp.MustCompile(`'fortran_order':\s*(True|False)`)
+	shapeRe   = regexp.MustCompile(`'shape':\s*\(([^)]*)\)`)
+)
+
+// unmarshalSklearnNPZ returns the tree encoding of a scikit-learn tree dump
+// saved with numpy.savez. The archive must contain the children_left,
+// children_right, feature, threshold and value arrays and may contain the
+// classes, feature_names and feature_types unicode arrays.
+func unmarshalSklearnNPZ(npzBytes []byte) (*treeJSON, error) {
+	zr, err := zip.NewReader(bytes.NewReader(npzBytes), int64(len(npzBytes)))
+	if err != nil {
+		return nil, err
+	}
+
+	arrays := make(map[string]npyArray)
+	for _, f := range zr.File {
+		rc, err := f.Open()
+		if err != nil {
+			return nil, err
+		}
+		b, err := io.ReadAll(rc)
+		rc.Close()
+		if err != nil {
+			return nil, err
+		}
+		arr, err := parseNPY(b)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", f.Name, err)
+		}
+		arrays[strings.TrimSuffix(f.Name, ".npy")] = arr
+	}
+
+	skt := &sklearnTree{}
+	for name, dst := range map[string]*[]int{
+		"children_left":  &skt.ChildrenLeft,
+		"children_right": &skt.ChildrenRight,
+		"feature":        &skt.Feature,
+	} {
+		arr, ok := arrays[name]
+		if !ok {
+			return nil, fmt.Errorf("Tree encoding error: missing %s array", name)
+		}
+		*dst = make([]int, len(arr.nums))
+		for i, v := range arr.nums {
+			(*dst)[i] = int(v)
+		}
+	}
+
+	th, ok := arrays["threshold"]
+	if !ok {
+		return nil, errors.New("Tree encoding error: missing threshold array")
+	}
+	skt.Threshold = th.nums
+
+	val, ok := arrays["value"]
+	if !ok {
+		return nil, errors.New("Tree encoding error: missing value array")
+	}
+	if len(val.shape) == 3 && val.shape[1] != 1 {
+		return nil, errors.New(
+			"Tree encoding error: multi-output trees are not supported",
+		)
+	}
+	if len(val.shape) < 2 {
+		return nil, errors.New("Tree encoding error: invalid value array shape")
+	}
+	nc := val.shape[len(val.shape)-1]
+	skt.Value = make([][]float64, val.shape[0])
+	for i := range skt.Value {
+		skt.Value[i] = val.nums[i*nc : (i+1)*nc]
+	}
+
+	skt.Classes = arrays["classes"].strs
+	if cls, ok := arrays["classes"]; ok && cls.strs == nil {
+		for _, v := range cls.nums {
+			skt.Classes = append(
+				skt.Classes,
+				strconv.FormatFloat(v, 'g', -1, 64),
+			)
+		}
+	}
+	skt.FeatureNames = arrays["feature_names"].strs
+	skt.FeatureTypes = arrays["feature_types"].strs
+
+	return skt.treeJSON()
+}
+
+// parseNPY returns the array encoded in the .npy file bytes b. Only little
+// endian integer, float and bool arrays and unicode string arrays in C order
+// are supported.
+func parseNPY(b []byte) (npyArray, error) {
+	if !bytes.HasPrefix(b, npyMagic) || len(b) < 10 {
+		return npyArray{}, errors.New("invalid npy file")
+	}
+
+	var hlen, start int
+	switch b[6] {
+	case 1:
+		hlen, start = int(binary.LittleEndian.Uint16(b[8:10])), 10
+	case 2, 3:
+		if len(b) < 12 {
+			return npyArray{}, errors.New("invalid npy file")
+		}
+		hlen, start = int(binary.LittleEndian.Uint32(b[8:12])), 12
+	default:
+		return npyArray{}, fmt.Errorf("unsupported npy version %d", b[6])
+	}
+	if len(b) < start+hlen {
+		return npyArray{}, errors.New("invalid npy header")
+	}
+	header, data := string(b[start:start+hlen]), b[start+hlen:]
+
+	descr := descrRe.FindStringSubmatch(header)
+	fortran := fortranRe.FindStringSubmatch(header)
+	shapeM := shapeRe.FindStringSubmatch(header)
+	if descr == nil || fortran == nil || shapeM == nil {
+		return npyArray{}, errors.New("invalid npy header")
+	}
+	if fortran[1] == "True" {
+		return npyArray{}, errors.New("fortran ordered arrays not supported")
+	}
+
+	arr := npyArray{}
+	count := 1
+	for _, d := range strings.Split(shapeM[1], ",") {
+		d = strings.TrimSpace(d)
+		if d == "" {
+			continue
+		}
+		n, err := strconv.Atoi(d)
+		if err != nil {
+			return npyArray{}, errors.New("invalid npy shape")
+		}
+		arr.shape = append(arr.shape, n)
+		count *= n
+	}
+
+	dt := descr[1]
+	if len(dt) < 3 || dt[0] == '>' {
+		return npyArray{}, fmt.Errorf("unsupported npy dtype %s", dt)
+	}
+	size, err := strconv.Atoi(dt[2:])
+	if err != nil {
+		return npyArray{}, fmt.Errorf("unsupported npy dtype %s", dt)
+	}
+	if dt[1] == 'U' {
+		size *= 4
+	}
+	if len(data) < count*size {
+		return npyArray{}, errors.New("truncated npy data")
+	}
+
+	for i := 0; i < count; i++ {
+		e := data[i*size : (i+1)*size]
+		switch {
+		case dt[1] == 'U':
+			arr.strs = append(arr.strs, decodeUTF32(e))
+		case dt[1] == 'f' && size == 8:
+			arr.nums = append(
+				arr.nums,
+				math.Float64frombits(binary.LittleEndian.Uint64(e)),
+			)
+		case dt[1] == 'f' && size == 4:
+			arr.nums = append(
+				arr.nums,
+				float64(math.Float32frombits(binary.LittleEndian.Uint32(e))),
+			)
+		case dt[1] == 'i' && size == 8:
+			arr.nums = append(arr.nums, float64(int64(binary.LittleEndian.Uint64(e))))
+		case dt[1] == 'i' && size == 4:
+			arr.nums = append(arr.nums, float64(int32(binary.LittleEndian.Uint32(e))))
+		case dt[1] == 'b' && size == 1:
+			arr.nums = append(arr.nums, float64(e[0]))
+		default:
+			return npyArray{}, fmt.Errorf("unsupported npy dtype %s", dt)
+		}
+	}
+
+	return arr, nil
+}
+
+// decodeUTF32 returns the string encoded in the little endian, zero padded,
+// UTF-32 bytes b.
+func decodeUTF32(b []byte) string {
+	var sb strings.Builder
+	for i := 0; i+4 <= len(b); i += 4 {
+		r := rune(binary.LittleEndian.Uint32(b[i : i+4]))
+		if r == 0 {
+			break
+		}
+		if !utf8.ValidRune(r) {
+			r = utf8.RuneError
+		}
+		sb.WriteRune(r)
+	}
+	return sb.String()
+}
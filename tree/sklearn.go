@@ -0,0 +1,172 @@
+package tree
+
+import (
+	"encoding/json"
+	"errors"
+	"strconv"
+)
+
+// sklearnLeaf is the child id scikit-learn uses to mark a node as a leaf.
+const sklearnLeaf = -1
+
+// sklearnTree holds the arrays exposed by the tree_ attribute of a
+// scikit-learn DecisionTreeClassifier alongside optional metadata.
+type sklearnTree struct {
+	ChildrenLeft  []int           `json:"children_left"`
+	ChildrenRight []int           `json:"children_right"`
+	Feature       []int           `json:"feature"`
+	Threshold     []float64       `json:"threshold"`
+	Value         [][]float64     `json:"-"`
+	RawValue      json.RawMessage `json:"value"`
+	// Optional metadata.
+	Classes      []string `json:"classes"`
+	Positive     string   `json:"positive"`
+	FeatureNames []string `json:"feature_names"`
+	FeatureTypes []string `json:"feature_types"`
+	NFeatures    int      `json:"n_features"`
+}
+
+// isSklearnJSON returns true if jsonBytes encodes a scikit-learn tree dump
+// instead of the custom tree schema.
+func isSklearnJSON(jsonBytes []byte) bool {
+	var probe struct {
+		ChildrenLeft json.RawMessage `json:"children_left"`
+	}
+	return json.Unmarshal(jsonBytes, &probe) == nil && probe.ChildrenLeft != nil
+}
+
+// unmarshalSklearnJSON returns the tree encoding of a scikit-learn tree dump
+// in json. The value array may have shape (nodes, classes) or (nodes, 1,
+// classes).
+func unmarshalSklearnJSON(jsonBytes []byte) (*treeJSON, error) {
+	skt := &sklearnTree{}
+	if err := json.Unmarshal(jsonBytes, skt); err != nil {
+		return nil, err
+	}
+
+	var value [][]float64
+	if err := json.Unmarshal(skt.RawValue, &value); err != nil {
+		var outValue [][][]float64
+		if err := json.Unmarshal(skt.RawValue, &outValue); err != nil {
+			return nil, errors.New(
+				"Tree encoding error: invalid value array shape",
+			)
+		}
+		value = make([][]float64, len(outValue))
+		for i, v := range outValue {
+			if len(v) != 1 {
+				return nil, errors.New(
+					"Tree encoding error: multi-output trees are not supported",
+				)
+			}
+			value[i] = v[0]
+		}
+	}
+	skt.Value = value
+
+	return skt.treeJSON()
+}
+
+// treeJSON returns the tree encoding equivalent to the scikit-learn arrays.
+func (skt *sklearnTree) treeJSON() (*treeJSON, error) {
+	n := len(skt.ChildrenLeft)
+	if n == 0 {
+		return nil, errors.New("Tree encoding error: tree has no nodes")
+	}
+	if len(skt.ChildrenRight) != n ||
+		len(skt.Feature) != n ||
+		len(skt.Threshold) != n ||
+		len(skt.Value) != n {
+		return nil, errors.New(
+			"Tree encoding error: node arrays lengths differ",
+		)
+	}
+
+	nClasses := len(skt.Value[0])
+	classes := skt.Classes
+	if classes == nil {
+		classes = make([]string, nClasses)
+		for i := range classes {
+			classes[i] = strconv.Itoa(i)
+		}
+	}
+	if len(classes) != nClasses {
+		return nil, errors.New(
+			"Tree encoding error: classes and value lengths differ",
+		)
+	}
+
+	features := skt.FeatureNames
+	if features == nil {
+		nf := skt.NFeatures
+		for _, f := range skt.Feature {
+			nf = max(nf, f+1)
+		}
+		features = make([]string, nf)
+		for i := range features {
+			features[i] = "x" + strconv.Itoa(i)
+		}
+	}
+
+	tj := newTreeJSON()
+	tj.ClassNames = classes
+	tj.Positive = skt.Positive
+	if tj.Positive == "" {
+		tj.Positive = classes[len(classes)-1]
+	}
+	tj.Features = features
+	// scikit-learn splits every feature on x <= threshold, so features are
+	// real unless stated otherwise.
+	tj.FeatureTypes = skt.FeatureTypes
+	if tj.FeatureTypes == nil {
+		tj.FeatureTypes = make([]string, len(features))
+		for i := range tj.FeatureTypes {
+			tj.FeatureTypes[i] = featReal
+		}
+	}
+
+	for i := 0; i < n; i++ {
+		if skt.ChildrenLeft[i] == sklearnLeaf {
+			if len(skt.Value[i]) != nClasses {
+				return nil, errors.New(
+					"Tree encoding error: value rows lengths differ",
+				)
+			}
+			tj.Nodes[i] = &nodeJSON{
+				ID:      i,
+				Type:    "leaf",
+				Class:   classes[argmax(skt.Value[i])],
+				FeatIdx: -1,
+				LeftID:  -1,
+				RightID: -1,
+			}
+			continue
+		}
+		th := skt.Threshold[i]
+		tj.Nodes[i] = &nodeJSON{
+			ID:        i,
+			Type:      "internal",
+			FeatIdx:   skt.Feature[i],
+			Threshold: &th,
+			LeftID:    skt.ChildrenLeft[i],
+			RightID:   skt.ChildrenRight[i],
+		}
+	}
+
+	if err := tj.Validate(); err != nil {
+		return nil, err
+	}
+
+	return tj, nil
+}
+
+// argmax returns the index of the first maximum value in vs.
+func argmax(vs []float64) int {
+	m := 0
+	for i, v := range vs {
+		if v > vs[m] {
+			m = i
+		}
+	}
+	return m
+}
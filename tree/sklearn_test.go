@@ -0,0 +1,234 @@
+package tree
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"math"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/jtcaraball/goexpdt/query"
+)
+
+var sklearnTest = struct {
+	left, right, feature []int
+	threshold            []float64
+	value                [][]float64
+}{
+	left:      []int{1, 3, -1, 5, 7, -1, -1, -1, 9, -1, -1},
+	right:     []int{2, 4, -1, 6, 8, -1, -1, -1, 10, -1, -1},
+	feature:   []int{6, 5, -2, 3, 7, -2, -2, -2, 4, -2, -2},
+	threshold: []float64{0.5, 0.5, -2, 0.5, 0.5, -2, -2, -2, 0.5, -2, -2},
+	value: [][]float64{
+		{3, 8}, {1, 8}, {2, 0}, {1, 1}, {0, 7}, {1, 0},
+		{0, 1}, {0, 4}, {0, 3}, {0, 2}, {0, 1},
+	},
+}
+
+func TestLoad_SklearnJSON(t *testing.T) {
+	tBytes := []byte(fmt.Sprintf(
+		`{
+			"children_left": %s,
+			"children_right": %s,
+			"feature": %s,
+			"threshold": %s,
+			"value": %s,
+			"classes": ["pos", "neg"],
+			"positive": "pos",
+			"feature_types": [%s],
+			"n_features": 10
+		}`,
+		jsonArray(sklearnTest.left),
+		jsonArray(sklearnTest.right),
+		jsonArray(sklearnTest.feature),
+		jsonArray(sklearnTest.threshold),
+		jsonArray(sklearnTest.value),
+		strings.TrimSuffix(strings.Repeat(`"binary", `, 10), ", "),
+	))
+	path, err := writeNewTree(t, tBytes)
+	if err != nil {
+		t.Fatalf("Failed to write tree file: %s", err.Error())
+	}
+	tTree, err := Load(path)
+	if err != nil {
+		t.Fatalf("Failed to load tree: %s", err.Error())
+	}
+	if tTree.Dim() != 10 {
+		t.Errorf("Wrong dimension. Expected 10 but got %d", tTree.Dim())
+	}
+	if !slices.Equal(test.nodes, tTree.Nodes()) {
+		t.Errorf(
+			"Trees not equal.\nExpected %v\nbut got  %v",
+			test.nodes,
+			tTree.Nodes(),
+		)
+	}
+}
+
+func TestLoad_SklearnNPZ(t *testing.T) {
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	value := []float64{}
+	for _, v := range sklearnTest.value {
+		value = append(value, v...)
+	}
+	arrays := []struct {
+		name  string
+		descr string
+		shape string
+		data  any
+	}{
+		{"children_left", "<i8", "(11,)", toInt64(sklearnTest.left)},
+		{"children_right", "<i8", "(11,)", toInt64(sklearnTest.right)},
+		{"feature", "<i8", "(11,)", toInt64(sklearnTest.feature)},
+		{"threshold", "<f8", "(11,)", sklearnTest.threshold},
+		{"value", "<f8", "(11, 1, 2)", value},
+		{"classes", "<U3", "(2,)", utf32("pos", 3, "neg", 3)},
+	}
+	for _, a := range arrays {
+		w, err := zw.Create(a.name + ".npy")
+		if err != nil {
+			t.Fatalf("Failed to write npz: %s", err.Error())
+		}
+		w.Write(npyBytes(a.descr, a.shape, a.data))
+	}
+	zw.Close()
+
+	tTree := tree{}
+	treeJSON, err := unmarshalSklearnNPZ(buf.Bytes())
+	if err != nil {
+		t.Fatalf("Failed to parse npz: %s", err.Error())
+	}
+	if err = tTree.populatetree(treeJSON); err != nil {
+		t.Fatalf("Failed to load tree: %s", err.Error())
+	}
+	if treeJSON.Positive != "neg" {
+		t.Errorf("Wrong default positive class %s", treeJSON.Positive)
+	}
+	// Features default to real, one per feature split on.
+	if tTree.Dim() != 5 {
+		t.Errorf("Wrong dimension. Expected 5 but got %d", tTree.Dim())
+	}
+	for i, n := range tTree.Nodes() {
+		if n.IsLeaf() && n.Value == test.nodes[i].Value {
+			t.Errorf("Leaf %d should have the opposite class", i)
+		}
+	}
+}
+
+func TestLoad_SklearnThresholds(t *testing.T) {
+	// x0 <= 2.5 is a, 2.5 < x0 <= 5.5 is b and 5.5 < x0 is a.
+	path, err := writeNewTree(t, []byte(`{
+		"children_left": [1, -1, 3, -1, -1],
+		"children_right": [2, -1, 4, -1, -1],
+		"feature": [0, -2, 0, -2, -2],
+		"threshold": [2.5, -2, 5.5, -2, -2],
+		"value": [[[2, 1]], [[1, 0]], [[1, 1]], [[0, 1]], [[1, 0]]],
+		"classes": ["a", "b"]
+	}`))
+	if err != nil {
+		t.Fatalf("Failed to write tree file: %s", err.Error())
+	}
+	tTree, err := Load(path)
+	if err != nil {
+		t.Fatalf("Failed to load tree: %s", err.Error())
+	}
+	expected := []string{"x0 > 2.5", "x0 > 5.5"}
+	if !slices.Equal(expected, tTree.FeatureNames()) {
+		t.Errorf(
+			"Feature names not equal.\nExpected %v\nbut got  %v",
+			expected,
+			tTree.FeatureNames(),
+		)
+	}
+	// The positive class defaults to b.
+	for _, tc := range []struct {
+		x        float64
+		positive bool
+	}{{1, false}, {2.5, false}, {4, true}, {5.5, true}, {7, false}} {
+		c, err := tTree.BinarizeInstance([]float64{tc.x})
+		if err != nil {
+			t.Fatalf("Failed to binarize instance: %s", err.Error())
+		}
+		if pos := tTree.isPositive(c); pos != tc.positive {
+			t.Errorf(
+				"Wrong class of x0 = %g. Expected positive %t but got %t",
+				tc.x,
+				tc.positive,
+				pos,
+			)
+		}
+	}
+}
+
+// isPositive returns true if the tree assigns the full instance c its
+// positive class.
+func (t *tree) isPositive(c query.QConst) bool {
+	n := t.root
+	for n.zeroChild != nil {
+		if c.Val[n.feat] == query.ONE {
+			n = n.oneChild
+		} else {
+			n = n.zeroChild
+		}
+	}
+	return n.value
+}
+
+func jsonArray(v any) string {
+	b, _ := json.Marshal(v)
+	return string(b)
+}
+
+func toInt64(vs []int) []int64 {
+	out := make([]int64, len(vs))
+	for i, v := range vs {
+		out[i] = int64(v)
+	}
+	return out
+}
+
+func utf32(args ...any) []uint32 {
+	out := []uint32{}
+	for i := 0; i < len(args); i += 2 {
+		s, n := args[i].(string), args[i+1].(int)
+		rs := []rune(s)
+		for j := 0; j < n; j++ {
+			if j < len(rs) {
+				out = append(out, uint32(rs[j]))
+				continue
+			}
+			out = append(out, 0)
+		}
+	}
+	return out
+}
+
+func npyBytes(descr, shape string, data any) []byte {
+	header := fmt.Sprintf(
+		"{'descr': '%s', 'fortran_order': False, 'shape': %s, }",
+		descr,
+		shape,
+	)
+	for (10+len(header)+1)%64 != 0 {
+		header += " "
+	}
+	header += "\n"
+	var buf bytes.Buffer
+	buf.Write(npyMagic)
+	buf.Write([]byte{1, 0})
+	binary.Write(&buf, binary.LittleEndian, uint16(len(header)))
+	buf.WriteString(header)
+	if f, ok := data.([]float64); ok {
+		for _, v := range f {
+			binary.Write(&buf, binary.LittleEndian, math.Float64bits(v))
+		}
+		return buf.Bytes()
+	}
+	binary.Write(&buf, binary.LittleEndian, data)
+	return buf.Bytes()
+}
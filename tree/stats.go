@@ -0,0 +1,149 @@
+package tree
+
+import (
+	"cmp"
+	"slices"
+)
+
+// Stats holds structural statistics of a tree.
+type Stats struct {
+	Features     int              `json:"features"`
+	Nodes        int              `json:"nodes"`
+	Depth        int              `json:"depth"`
+	Leaves       int              `json:"leaves"`
+	ClassLeaves  map[string]int   `json:"class_leaves"`
+	FeaturesUsed int              `json:"features_used"`
+	FeatureUsage []FeatureUsage   `json:"feature_usage"`
+	PathLengths  []PathLengthFreq `json:"path_lengths"`
+}
+
+// FeatureUsage is the number of internal nodes splitting on a feature.
+type FeatureUsage struct {
+	Feature string `json:"feature"`
+	Splits  int    `json:"splits"`
+}
+
+// PathLengthFreq is the number of leaves at a given depth.
+type PathLengthFreq struct {
+	Length int `json:"length"`
+	Leaves int `json:"leaves"`
+}
+
+// Depth returns the length of the longest path from the tree's root to a
+// leaf. Returns 0 if t is nil.
+func (t *tree) Depth() int {
+	if t == nil {
+		return 0
+	}
+	return len(t.PathLengths()) - 1
+}
+
+// PathLengths returns the number of leaves of the tree at every depth, indexed
+// by depth. Returns nil if t is nil.
+func (t *tree) PathLengths() []int {
+	if t == nil {
+		return nil
+	}
+	var lengths []int
+	t.walk(func(n *node, depth int) {
+		if n.zeroChild != nil {
+			return
+		}
+		for len(lengths) <= depth {
+			lengths = append(lengths, 0)
+		}
+		lengths[depth] += 1
+	})
+	return lengths
+}
+
+// ClassLeaves returns the number of leaves of the tree per class. Returns nil
+// if t is nil.
+func (t *tree) ClassLeaves() map[string]int {
+	if t == nil {
+		return nil
+	}
+	counts := make(map[string]int, len(t.classes))
+	for _, c := range t.classes {
+		counts[c] = 0
+	}
+	t.walk(func(n *node, _ int) {
+		if n.zeroChild == nil {
+			counts[n.class] += 1
+		}
+	})
+	return counts
+}
+
+// FeatureUsage returns the number of internal nodes splitting on every
+// feature of the tree's binarized feature space, indexed by feature. Returns
+// nil if t is nil.
+func (t *tree) FeatureUsage() []int {
+	if t == nil {
+		return nil
+	}
+	usage := make([]int, t.featCount)
+	t.walk(func(n *node, _ int) {
+		if n.zeroChild != nil {
+			usage[n.feat] += 1
+		}
+	})
+	return usage
+}
+
+// Stats returns the structural statistics of the tree. Feature usage only
+// lists the features the tree splits on, most used first. Returns empty
+// statistics if t is nil.
+func (t *tree) Stats() Stats {
+	if t == nil {
+		return Stats{}
+	}
+	s := Stats{
+		Features:    t.Dim(),
+		Nodes:       t.nodeCount,
+		Depth:       t.Depth(),
+		ClassLeaves: t.ClassLeaves(),
+	}
+
+	names := t.FeatureNames()
+	for i, splits := range t.FeatureUsage() {
+		if splits == 0 {
+			continue
+		}
+		s.FeaturesUsed += 1
+		s.FeatureUsage = append(s.FeatureUsage, FeatureUsage{names[i], splits})
+	}
+	slices.SortStableFunc(s.FeatureUsage, func(a, b FeatureUsage) int {
+		return cmp.Compare(b.Splits, a.Splits)
+	})
+
+	for l, leaves := range t.PathLengths() {
+		s.Leaves += leaves
+		if leaves > 0 {
+			s.PathLengths = append(s.PathLengths, PathLengthFreq{l, leaves})
+		}
+	}
+
+	return s
+}
+
+// walk calls visit on every node of the tree along with its depth.
+func (t *tree) walk(visit func(n *node, depth int)) {
+	type depthElem struct {
+		n     *node
+		depth int
+	}
+	toVisit := []depthElem{{t.root, 0}}
+	for len(toVisit) > 0 {
+		el := toVisit[len(toVisit)-1]
+		toVisit = toVisit[:len(toVisit)-1]
+		visit(el.n, el.depth)
+		if el.n.zeroChild != nil {
+			toVisit = append(
+				toVisit,
+				depthElem{el.n.oneChild, el.depth + 1},
+				depthElem{el.n.zeroChild, el.depth + 1},
+			)
+		}
+	}
+}
@@ -0,0 +1,46 @@
+package tree
+
+import (
+	"maps"
+	"slices"
+	"testing"
+)
+
+func TestStats(t *testing.T) {
+	path, err := writeNewTree(t, test.tBytes)
+	if err != nil {
+		t.Fatalf("Failed to write tree file: %s", err.Error())
+	}
+	tTree, err := Load(path)
+	if err != nil {
+		t.Fatalf("Failed to load tree: %s", err.Error())
+	}
+
+	expected := Stats{
+		Features:     10,
+		Nodes:        11,
+		Depth:        4,
+		Leaves:       6,
+		ClassLeaves:  map[string]int{"pos": 2, "neg": 4},
+		FeaturesUsed: 5,
+		FeatureUsage: []FeatureUsage{
+			{"ft4", 1}, {"ft5", 1}, {"ft6", 1}, {"ft7", 1}, {"ft8", 1},
+		},
+		PathLengths: []PathLengthFreq{{1, 1}, {3, 3}, {4, 2}},
+	}
+	s := tTree.Stats()
+	if s.Features != expected.Features ||
+		s.Nodes != expected.Nodes ||
+		s.Depth != expected.Depth ||
+		s.Leaves != expected.Leaves ||
+		s.FeaturesUsed != expected.FeaturesUsed ||
+		!maps.Equal(s.ClassLeaves, expected.ClassLeaves) ||
+		!slices.Equal(s.FeatureUsage, expected.FeatureUsage) ||
+		!slices.Equal(s.PathLengths, expected.PathLengths) {
+		t.Errorf(
+			"Stats not equal.\nExpected %+v\nbut got  %+v",
+			expected,
+			s,
+		)
+	}
+}
@@ -0,0 +1,255 @@
+package tree
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"slices"
+	"strconv"
+)
+
+// Truncate returns the tree obtained by replacing every subtree rooted at
+// the given depth by a leaf of its majority class, the class of most of its
+// leafs with ties broken by the order of the tree's classes.
+func (t *tree) Truncate(depth int) (tree, error) {
+	if depth < 0 {
+		return tree{}, errors.New("Truncation depth must not be negative")
+	}
+	return t.derive(func(n *node, d int) (string, bool) {
+		if d < depth || n.zeroChild == nil {
+			return "", false
+		}
+		return t.majorityClass(n), true
+	}, false)
+}
+
+// Collapse returns the tree obtained by replacing every split whose
+// subtrees only have leafs of the same class by a leaf of that class. The
+// classification of every instance is preserved.
+func (t *tree) Collapse() (tree, error) {
+	return t.derive(func(n *node, _ int) (string, bool) {
+		if n.zeroChild == nil {
+			return "", false
+		}
+		return uniformClass(n)
+	}, false)
+}
+
+// Project returns the same tree over only the original features it splits
+// on, so its dimension only counts features it tests. Trees made of a single
+// leaf can not be projected.
+func (t *tree) Project() (tree, error) {
+	if t.root.zeroChild == nil {
+		return tree{}, errors.New("Tree splits on no feature to project on")
+	}
+	return t.derive(func(*node, int) (string, bool) { return "", false }, true)
+}
+
+// WriteJSON writes the tree to w in the custom tree json schema. Nodes are
+// renumbered from 0 at the root, siblings getting consecutive ids.
+func (t *tree) WriteJSON(w io.Writer) error {
+	tj := t.encode(func(*node, int) (string, bool) { return "", false }, false)
+
+	type leafJSON struct {
+		ID    int    `json:"id"`
+		Type  string `json:"type"`
+		Class string `json:"class"`
+	}
+	type internalJSON struct {
+		ID        int      `json:"id"`
+		Type      string   `json:"type"`
+		FeatIdx   int      `json:"feature_index"`
+		Threshold *float64 `json:"threshold,omitempty"`
+		LeftID    int      `json:"id_left"`
+		RightID   int      `json:"id_right"`
+	}
+
+	nodes := make(map[string]any, len(tj.Nodes))
+	for id, n := range tj.Nodes {
+		if n.Type == "leaf" {
+			nodes[strconv.Itoa(id)] = leafJSON{id, n.Type, n.Class}
+			continue
+		}
+		nodes[strconv.Itoa(id)] = internalJSON{
+			id,
+			n.Type,
+			n.FeatIdx,
+			n.Threshold,
+			n.LeftID,
+			n.RightID,
+		}
+	}
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(struct {
+		ClassNames   []string       `json:"class_names"`
+		Positive     string         `json:"positive"`
+		Features     []string       `json:"feature_names"`
+		FeatureTypes []string       `json:"feature_types,omitempty"`
+		Nodes        map[string]any `json:"nodes"`
+	}{tj.ClassNames, tj.Positive, tj.Features, tj.FeatureTypes, nodes})
+}
+
+// derive returns the tree encoded by t.encode(leafAt, project).
+func (t *tree) derive(
+	leafAt func(n *node, depth int) (string, bool),
+	project bool,
+) (tree, error) {
+	d := tree{}
+	if err := d.populatetree(t.encode(leafAt, project)); err != nil {
+		return tree{}, err
+	}
+	return d, nil
+}
+
+// encode returns the encoding of the tree with the subtrees rooted at the
+// nodes n at depth for which leafAt(n, depth) returns true replaced by a
+// leaf of the returned class. Nodes are renumbered from 0 at the root,
+// siblings getting consecutive ids. If project is true the encoding only
+// holds the original features split on by the encoded nodes.
+func (t *tree) encode(
+	leafAt func(n *node, depth int) (string, bool),
+	project bool,
+) *treeJSON {
+	tj := newTreeJSON()
+	tj.ClassNames = t.classes
+	tj.Positive = t.positive
+
+	type encElem struct {
+		n     *node
+		id    int
+		depth int
+	}
+	next := 1
+	toVisit := []encElem{{t.root, 0, 0}}
+	for len(toVisit) > 0 {
+		el := toVisit[len(toVisit)-1]
+		toVisit = toVisit[:len(toVisit)-1]
+
+		class, isLeaf := el.n.class, el.n.zeroChild == nil
+		if c, ok := leafAt(el.n, el.depth); ok {
+			class, isLeaf = c, true
+		}
+		if isLeaf {
+			tj.Nodes[el.id] = &nodeJSON{
+				ID:      el.id,
+				Type:    "leaf",
+				Class:   class,
+				FeatIdx: -1,
+				LeftID:  -1,
+				RightID: -1,
+			}
+			continue
+		}
+
+		f := t.feats[el.n.feat]
+		nj := &nodeJSON{
+			ID:      el.id,
+			Type:    "internal",
+			FeatIdx: f.orig,
+			LeftID:  next,
+			RightID: next + 1,
+		}
+		if f.real {
+			th := f.threshold
+			nj.Threshold = &th
+		}
+		tj.Nodes[el.id] = nj
+		next += 2
+		toVisit = append(
+			toVisit,
+			encElem{el.n.oneChild, nj.RightID, el.depth + 1},
+			encElem{el.n.zeroChild, nj.LeftID, el.depth + 1},
+		)
+	}
+
+	// Original features are kept in order, the projection only dropping
+	// those no encoded node splits on.
+	idx := make([]int, len(t.origNames))
+	for i := range idx {
+		idx[i] = i
+	}
+	if project {
+		used := make([]bool, len(t.origNames))
+		for _, n := range tj.Nodes {
+			if n.Type == "internal" {
+				used[n.FeatIdx] = true
+			}
+		}
+		k := 0
+		for i := range idx {
+			idx[i] = -1
+			if used[i] {
+				idx[i] = k
+				k += 1
+			}
+		}
+		for _, n := range tj.Nodes {
+			if n.Type == "internal" {
+				n.FeatIdx = idx[n.FeatIdx]
+			}
+		}
+	}
+
+	hasReal := slices.Contains(t.origReal, true)
+	for i, name := range t.origNames {
+		if idx[i] < 0 {
+			continue
+		}
+		tj.Features = append(tj.Features, name)
+		if !hasReal {
+			continue
+		}
+		if t.origReal[i] {
+			tj.FeatureTypes = append(tj.FeatureTypes, featReal)
+		} else {
+			tj.FeatureTypes = append(tj.FeatureTypes, featBinary)
+		}
+	}
+
+	return tj
+}
+
+// majorityClass returns the class of most of the leafs of the subtree rooted
+// at n, ties broken by the order of the tree's classes.
+func (t *tree) majorityClass(n *node) string {
+	counts := make(map[string]int)
+	toVisit := []*node{n}
+	for len(toVisit) > 0 {
+		n := toVisit[len(toVisit)-1]
+		toVisit = toVisit[:len(toVisit)-1]
+		if n.zeroChild == nil {
+			counts[n.class] += 1
+			continue
+		}
+		toVisit = append(toVisit, n.oneChild, n.zeroChild)
+	}
+	best := t.classes[0]
+	for _, c := range t.classes {
+		if counts[c] > counts[best] {
+			best = c
+		}
+	}
+	return best
+}
+
+// uniformClass returns the class of the leafs of the subtree rooted at n and
+// true if they all share it.
+func uniformClass(n *node) (string, bool) {
+	class := ""
+	toVisit := []*node{n}
+	for len(toVisit) > 0 {
+		n := toVisit[len(toVisit)-1]
+		toVisit = toVisit[:len(toVisit)-1]
+		if n.zeroChild != nil {
+			toVisit = append(toVisit, n.oneChild, n.zeroChild)
+			continue
+		}
+		if class != "" && n.class != class {
+			return "", false
+		}
+		class = n.class
+	}
+	return class, true
+}
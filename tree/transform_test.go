@@ -0,0 +1,157 @@
+package tree
+
+import (
+	"bytes"
+	"maps"
+	"slices"
+	"testing"
+
+	"github.com/jtcaraball/goexpdt/query"
+)
+
+func loadTestTree(t *testing.T, tBytes []byte) tree {
+	t.Helper()
+	path, err := writeNewTree(t, tBytes)
+	if err != nil {
+		t.Fatalf("Failed to write tree file: %s", err.Error())
+	}
+	tTree, err := Load(path)
+	if err != nil {
+		t.Fatalf("Failed to load tree: %s", err.Error())
+	}
+	return tTree
+}
+
+func TestTruncate(t *testing.T) {
+	tTree := loadTestTree(t, test.tBytes)
+	tr, err := tTree.Truncate(2)
+	if err != nil {
+		t.Fatalf("Failed to truncate tree: %s", err.Error())
+	}
+	if tr.Dim() != 10 {
+		t.Errorf("Wrong dimension. Expected 10 but got %d", tr.Dim())
+	}
+	s := tr.Stats()
+	if s.Nodes != 5 || s.Depth != 2 {
+		t.Errorf(
+			"Wrong shape. Expected 5 nodes of depth 2 but got %d of depth %d",
+			s.Nodes,
+			s.Depth,
+		)
+	}
+	// The tie between the leafs under node 3 goes to the first class.
+	expected := map[string]int{"pos": 2, "neg": 1}
+	if !maps.Equal(expected, s.ClassLeaves) {
+		t.Errorf(
+			"Leafs per class not equal.\nExpected %v\nbut got  %v",
+			expected,
+			s.ClassLeaves,
+		)
+	}
+
+	if _, err = tTree.Truncate(-1); err == nil {
+		t.Error("Expected error for negative depth")
+	}
+}
+
+func TestCollapse(t *testing.T) {
+	tTree := loadTestTree(t, test.tBytes)
+	col, err := tTree.Collapse()
+	if err != nil {
+		t.Fatalf("Failed to collapse tree: %s", err.Error())
+	}
+	if n := len(col.Nodes()); n != 7 {
+		t.Errorf("Wrong node count. Expected 7 but got %d", n)
+	}
+
+	c := query.AllBotConst(tTree.Dim())
+	for i := 0; i < 1<<tTree.Dim(); i++ {
+		for j := range c.Val {
+			c.Val[j] = query.ZERO
+			if i&(1<<j) != 0 {
+				c.Val[j] = query.ONE
+			}
+		}
+		if tTree.classify(c) != col.classify(c) {
+			t.Fatalf("Classification of %s changed", c.AsString())
+		}
+	}
+}
+
+func TestProject(t *testing.T) {
+	tTree := loadTestTree(t, test.tBytes)
+	p, err := tTree.Project()
+	if err != nil {
+		t.Fatalf("Failed to project tree: %s", err.Error())
+	}
+	expected := []string{"ft4", "ft5", "ft6", "ft7", "ft8"}
+	if !slices.Equal(expected, p.FeatureNames()) {
+		t.Errorf(
+			"Feature names not equal.\nExpected %v\nbut got  %v",
+			expected,
+			p.FeatureNames(),
+		)
+	}
+	if p.Dim() != 5 {
+		t.Errorf("Wrong dimension. Expected 5 but got %d", p.Dim())
+	}
+	if len(p.Nodes()) != len(tTree.Nodes()) {
+		t.Errorf(
+			"Wrong node count. Expected %d but got %d",
+			len(tTree.Nodes()),
+			len(p.Nodes()),
+		)
+	}
+
+	leaf, err := tTree.Truncate(0)
+	if err != nil {
+		t.Fatalf("Failed to truncate tree: %s", err.Error())
+	}
+	if _, err = leaf.Project(); err == nil {
+		t.Error("Expected error projecting a single leaf")
+	}
+}
+
+func TestWriteJSON(t *testing.T) {
+	for _, tBytes := range [][]byte{test.tBytes, realTest.tBytes} {
+		tTree := loadTestTree(t, tBytes)
+		var buf bytes.Buffer
+		if err := tTree.WriteJSON(&buf); err != nil {
+			t.Fatalf("Failed to write tree: %s", err.Error())
+		}
+		rTree := loadTestTree(t, buf.Bytes())
+		if !slices.Equal(tTree.FeatureNames(), rTree.FeatureNames()) {
+			t.Errorf(
+				"Feature names not equal.\nExpected %v\nbut got  %v",
+				tTree.FeatureNames(),
+				rTree.FeatureNames(),
+			)
+		}
+
+		// Nodes are renumbered so the round trip is compared through its
+		// encoding and the classification of every instance.
+		var rBuf bytes.Buffer
+		if err := rTree.WriteJSON(&rBuf); err != nil {
+			t.Fatalf("Failed to write tree: %s", err.Error())
+		}
+		if !bytes.Equal(buf.Bytes(), rBuf.Bytes()) {
+			t.Errorf(
+				"Encodings not equal.\nExpected %s\nbut got  %s",
+				buf.String(),
+				rBuf.String(),
+			)
+		}
+		c := query.AllBotConst(tTree.Dim())
+		for i := 0; i < 1<<tTree.Dim(); i++ {
+			for j := range c.Val {
+				c.Val[j] = query.ZERO
+				if i&(1<<j) != 0 {
+					c.Val[j] = query.ONE
+				}
+			}
+			if tTree.classify(c) != rTree.classify(c) {
+				t.Fatalf("Classification of %s changed", c.AsString())
+			}
+		}
+	}
+}
@@ -6,6 +6,8 @@ package tree
 import (
 	"fmt"
 	"os"
+	"path/filepath"
+	"slices"
 
 	"github.com/jtcaraball/goexpdt/query"
 )
@@ -15,6 +17,7 @@ import (
 type node struct {
 	id        int
 	feat      int
+	class     string
 	value     bool
 	zeroChild *node
 	oneChild  *node
@@ -26,19 +29,37 @@ type tree struct {
 	root          *node
 	nodeCount     int
 	featCount     int
+	feats         []feature
+	origNames     []string
+	origReal      []bool
+	classes       []string
+	positive      string
 	nodes         []query.Node
 	nodeConsts    []query.QConst
 	posLeafConsts []query.QConst
 	negLeafConsts []query.QConst
 }
 
-// Load returns the tree encoded in a json files passed by path.
+// Load returns the tree encoded in the file passed by path. The file may use
+// the custom tree json schema, be a dump of scikit-learn's tree_ arrays,
+// either as json or as a numpy .npz archive, or encode a random forest or
+// boosted trees ensemble, which is compiled into a single equivalent tree.
 func Load(path string) (tree, error) {
-	jsonBytes, err := os.ReadFile(path)
+	fileBytes, err := os.ReadFile(path)
 	if err != nil {
 		return tree{}, err
 	}
-	treeJSON, err := unmarhsalTree(jsonBytes)
+	var treeJSON *treeJSON
+	switch {
+	case filepath.Ext(path) == ".npz":
+		treeJSON, err = unmarshalSklearnNPZ(fileBytes)
+	case isEnsembleJSON(fileBytes):
+		treeJSON, err = unmarshalEnsembleJSON(fileBytes)
+	case isSklearnJSON(fileBytes):
+		treeJSON, err = unmarshalSklearnJSON(fileBytes)
+	default:
+		treeJSON, err = unmarhsalTree(fileBytes)
+	}
 	if err != nil {
 		return tree{}, err
 	}
@@ -56,8 +77,13 @@ type visitElem struct {
 }
 
 func (t *tree) populatetree(treeJSON *treeJSON) error {
-	t.featCount = len(treeJSON.Features)
+	t.buildFeatureSpace(treeJSON)
 	t.nodeCount = len(treeJSON.Nodes)
+	t.classes = treeJSON.ClassNames
+	t.positive = treeJSON.Positive
+	if t.positive == "" {
+		t.positive = t.classes[len(t.classes)-1]
+	}
 
 	toVisit := []visitElem{{ID: 0}}
 
@@ -84,11 +110,12 @@ func (t *tree) populatetree(treeJSON *treeJSON) error {
 		}
 
 		if nodeJSON.Type == "leaf" {
-			node.value = nodeJSON.Class == treeJSON.Positive
+			node.class = nodeJSON.Class
+			node.value = node.class == t.positive
 			continue
 		}
 
-		node.feat = nodeJSON.FeatIdx
+		node.feat = t.featIndex(nodeJSON.FeatIdx, nodeJSON.Threshold)
 
 		toVisit = append(
 			toVisit,
@@ -100,6 +127,42 @@ func (t *tree) populatetree(treeJSON *treeJSON) error {
 	return nil
 }
 
+// Classes returns the names of the classes the tree classifies into.
+func (t *tree) Classes() []string {
+	return t.classes
+}
+
+// Positive returns the name of the class whose leafs are positive.
+func (t *tree) Positive() string {
+	return t.positive
+}
+
+// SetPositive sets class as the tree's positive class, making every other
+// class negative. Returns an error if class is not one of the tree's classes.
+func (t *tree) SetPositive(class string) error {
+	if !slices.Contains(t.classes, class) {
+		return fmt.Errorf("tree error: class '%s' does not exist", class)
+	}
+
+	t.positive = class
+	t.posLeafConsts, t.negLeafConsts = nil, nil
+
+	toVisit := []*node{t.root}
+	for len(toVisit) > 0 {
+		n := toVisit[len(toVisit)-1]
+		toVisit = toVisit[:len(toVisit)-1]
+		n.value = n.class == class
+		if n.zeroChild != nil {
+			toVisit = append(toVisit, n.zeroChild)
+		}
+		if n.oneChild != nil {
+			toVisit = append(toVisit, n.oneChild)
+		}
+	}
+
+	return nil
+}
+
 // Nodes returns a slices of the query.Node(s) that compose the tree. Returns
 // an empty slice if t is nil.
 func (t *tree) Nodes() []query.Node {
@@ -171,8 +234,8 @@ func (t *tree) NodesConsts() []query.QConst {
 		ninfo, nstack = nstack[len(nstack)-1], nstack[:len(nstack)-1]
 		n, v = ninfo.n, ninfo.v
 
-		next += 1
 		nconsts[next] = query.QConst{Val: v}
+		next += 1
 
 		if n.zeroChild == nil || n.oneChild == nil {
 			continue
@@ -185,7 +248,11 @@ func (t *tree) NodesConsts() []query.QConst {
 		zv[n.feat] = query.ZERO
 		ov[n.feat] = query.ONE
 
-		nstack = append(nstack, nodeElem{n, zv}, nodeElem{n, zv})
+		nstack = append(
+			nstack,
+			nodeElem{n.zeroChild, zv},
+			nodeElem{n.oneChild, ov},
+		)
 	}
 
 	t.nodeConsts = nconsts
@@ -258,7 +325,11 @@ func (t *tree) computeLeafs() {
 		zv[n.feat] = query.ZERO
 		ov[n.feat] = query.ONE
 
-		nstack = append(nstack, nodeElem{n, zv}, nodeElem{n, zv})
+		nstack = append(
+			nstack,
+			nodeElem{n.zeroChild, zv},
+			nodeElem{n.oneChild, ov},
+		)
 	}
 
 	t.posLeafConsts = pconsts
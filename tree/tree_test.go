@@ -132,26 +132,26 @@ var test = struct {
 	},
 	nodeConsts: []query.QConst{
 		{Val: []query.FeatV{b, b, b, b, b, b, b, b, b, b}},
-		{Val: []query.FeatV{b, b, b, b, b, z, b, b, b, b}},
-		{Val: []query.FeatV{b, b, b, b, b, o, b, b, b, b}},
-		{Val: []query.FeatV{b, b, b, b, z, z, b, b, b, b}},
-		{Val: []query.FeatV{b, b, b, b, o, z, b, b, b, b}},
-		{Val: []query.FeatV{b, b, z, b, z, z, b, b, b, b}},
-		{Val: []query.FeatV{b, b, o, b, z, z, b, b, b, b}},
-		{Val: []query.FeatV{b, b, b, b, o, z, z, b, b, b}},
-		{Val: []query.FeatV{b, b, b, b, o, z, o, b, b, b}},
-		{Val: []query.FeatV{b, b, b, z, o, z, o, b, b, b}},
-		{Val: []query.FeatV{b, b, b, o, o, z, o, b, b, b}},
+		{Val: []query.FeatV{b, b, b, b, b, b, z, b, b, b}},
+		{Val: []query.FeatV{b, b, b, b, b, b, o, b, b, b}},
+		{Val: []query.FeatV{b, b, b, b, b, z, z, b, b, b}},
+		{Val: []query.FeatV{b, b, b, b, b, o, z, b, b, b}},
+		{Val: []query.FeatV{b, b, b, z, b, z, z, b, b, b}},
+		{Val: []query.FeatV{b, b, b, o, b, z, z, b, b, b}},
+		{Val: []query.FeatV{b, b, b, b, b, o, z, z, b, b}},
+		{Val: []query.FeatV{b, b, b, b, b, o, z, o, b, b}},
+		{Val: []query.FeatV{b, b, b, b, z, o, z, o, b, b}},
+		{Val: []query.FeatV{b, b, b, b, o, o, z, o, b, b}},
 	},
 	posLeafConsts: []query.QConst{
-		{Val: []query.FeatV{b, b, b, b, b, o, b, b, b, b}},
-		{Val: []query.FeatV{b, b, z, b, z, z, b, b, b, b}},
+		{Val: []query.FeatV{b, b, b, b, b, b, o, b, b, b}},
+		{Val: []query.FeatV{b, b, b, z, b, z, z, b, b, b}},
 	},
 	negLeafConsts: []query.QConst{
-		{Val: []query.FeatV{b, b, o, b, z, z, b, b, b, b}},
-		{Val: []query.FeatV{b, b, b, b, o, z, z, b, b, b}},
-		{Val: []query.FeatV{b, b, b, z, o, z, o, b, b, b}},
-		{Val: []query.FeatV{b, b, b, o, o, z, o, b, b, b}},
+		{Val: []query.FeatV{b, b, b, o, b, z, z, b, b, b}},
+		{Val: []query.FeatV{b, b, b, b, b, o, z, z, b, b}},
+		{Val: []query.FeatV{b, b, b, b, z, o, z, o, b, b}},
+		{Val: []query.FeatV{b, b, b, b, o, o, z, o, b, b}},
 	},
 }
 
@@ -183,8 +183,9 @@ func TestMain(m *testing.M) {
 	n2 := node{id: 2, value: true}
 	n1 := node{id: 1, feat: 5, zeroChild: &n3, oneChild: &n4}
 	test.tree.root = &node{id: 0, feat: 6, zeroChild: &n1, oneChild: &n2}
-	test.tree.nodeCount = 10
+	test.tree.nodeCount = 11
 	test.tree.featCount = 10
+	os.Exit(m.Run())
 }
 
 func TestLoad_Nodes(t *testing.T) {
@@ -216,6 +217,7 @@ func Test_NodeConsts(t *testing.T) {
 	for _, c := range test.nodeConsts {
 		expnc = append(expnc, c.AsString())
 	}
+	slices.Sort(expnc)
 
 	if !slices.Equal(nc, expnc) {
 		t.Errorf("Nodes not equal.\nExpected %s.\nbut got  %s", expnc, nc)
@@ -233,6 +235,7 @@ func Test_PosLeafConsts(t *testing.T) {
 	for _, c := range test.posLeafConsts {
 		expnc = append(expnc, c.AsString())
 	}
+	slices.Sort(expnc)
 
 	if !slices.Equal(nc, expnc) {
 		t.Errorf("Pos leafs not equal.\nExpected %s.\nbut got  %s", expnc, nc)
@@ -250,8 +253,51 @@ func Test_NegLeafConsts(t *testing.T) {
 	for _, c := range test.negLeafConsts {
 		expnc = append(expnc, c.AsString())
 	}
+	slices.Sort(expnc)
 
 	if !slices.Equal(nc, expnc) {
 		t.Errorf("Neg leafs not equal.\nExpected %s.\nbut got  %s", expnc, nc)
 	}
 }
+
+func TestSetPositive_MultiClass(t *testing.T) {
+	path, err := writeNewTree(t, []byte(`
+{
+	"class_names": ["a", "b", "c"],
+	"feature_names": ["f0", "f1"],
+	"nodes": {
+		"0": {"id": 0, "type": "internal", "feature_index": 0, "id_left": 1, "id_right": 2},
+		"1": {"id": 1, "type": "leaf", "class": "a"},
+		"2": {"id": 2, "type": "internal", "feature_index": 1, "id_left": 3, "id_right": 4},
+		"3": {"id": 3, "type": "leaf", "class": "b"},
+		"4": {"id": 4, "type": "leaf", "class": "c"}
+	}
+}
+	`))
+	if err != nil {
+		t.Fatalf("Failed to write tree file: %s", err.Error())
+	}
+	tTree, err := Load(path)
+	if err != nil {
+		t.Fatalf("Failed to load tree: %s", err.Error())
+	}
+	if tTree.Positive() != "c" {
+		t.Errorf("Wrong default positive class %s", tTree.Positive())
+	}
+
+	for i, class := range tTree.Classes() {
+		if err = tTree.SetPositive(class); err != nil {
+			t.Fatalf("Failed to set positive class: %s", err.Error())
+		}
+		leafs := []int{1, 3, 4}
+		for j, n := range leafs {
+			if tTree.Nodes()[n].Value != (i == j) {
+				t.Errorf("Wrong value of leaf %d for class %s", n, class)
+			}
+		}
+	}
+
+	if err = tTree.SetPositive("d"); err == nil {
+		t.Error("Expected error for unknown class")
+	}
+}
@@ -3,6 +3,7 @@ package tree
 import (
 	"encoding/json"
 	"errors"
+	"fmt"
 	"slices"
 )
 
@@ -12,17 +13,31 @@ type nodeJSON struct {
 	// Leaf fields
 	Class string `json:"class"`
 	// Internal fields
-	FeatIdx int `json:"feature_index"`
-	LeftID  int `json:"id_left"`
-	RightID int `json:"id_right"`
+	FeatIdx   int      `json:"feature_index"`
+	Threshold *float64 `json:"threshold"`
+	LeftID    int      `json:"id_left"`
+	RightID   int      `json:"id_right"`
 }
 
+// Feature types. Binary features, also named boolean, are mapped to a single
+// Boolean feature while real features are mapped to one Boolean feature per
+// distinct threshold the tree splits them on.
+const (
+	featBinary  = "binary"
+	featBoolean = "boolean"
+	featReal    = "real"
+)
+
 type treeJSON struct {
-	ClassNames []string                   `json:"class_names"`
-	Positive   string                     `json:"positive"`
-	Features   []string                   `json:"feature_names"`
-	RawNodes   map[string]json.RawMessage `json:"nodes"`
-	Nodes      map[int]*nodeJSON          `json:"-"`
+	ClassNames   []string                   `json:"class_names"`
+	Positive     string                     `json:"positive"`
+	Features     []string                   `json:"feature_names"`
+	FeatureTypes []string                   `json:"feature_types"`
+	RawNodes     map[string]json.RawMessage `json:"nodes"`
+	Nodes        map[int]*nodeJSON          `json:"-"`
+	// dupIDs holds the ids shared by more than one encoded node, of which
+	// only the last is kept in Nodes.
+	dupIDs []int
 }
 
 func newTreeJSON() *treeJSON {
@@ -46,6 +61,9 @@ func unmarhsalTree(jsonBytes []byte) (*treeJSON, error) {
 		if err := json.Unmarshal(nodeBytes, nodeJSON); err != nil {
 			return nil, err
 		}
+		if _, ok := treeJSON.Nodes[nodeJSON.ID]; ok {
+			treeJSON.dupIDs = append(treeJSON.dupIDs, nodeJSON.ID)
+		}
 		treeJSON.Nodes[nodeJSON.ID] = nodeJSON
 	}
 	if err := treeJSON.Validate(); err != nil {
@@ -54,34 +72,87 @@ func unmarhsalTree(jsonBytes []byte) (*treeJSON, error) {
 	return treeJSON, nil
 }
 
+// Validate returns the joined errors of every problem found in the encoding,
+// covering both its fields and the structure of its nodes, or nil if there
+// is none.
 func (tj treeJSON) Validate() error {
+	return errors.Join(tj.problems()...)
+}
+
+// problems returns every problem found in the encoding.
+func (tj treeJSON) problems() []error {
+	var probs []error
 	// Validate fields
-	if len(tj.ClassNames) != 2 {
-		return errors.New(
-			"Tree encoding error: must have exactly two class_names",
-		)
+	if len(tj.ClassNames) < 2 {
+		probs = append(probs, errors.New(
+			"Tree encoding error: must have at least two class_names",
+		))
 	}
-	if !slices.Contains(tj.ClassNames, tj.Positive) {
-		return errors.New(
+	if tj.Positive != "" && !slices.Contains(tj.ClassNames, tj.Positive) {
+		probs = append(probs, errors.New(
 			"Tree encoding error: positive must be contained in class_names",
-		)
+		))
 	}
 	if len(tj.Features) == 0 {
-		return errors.New(
+		probs = append(probs, errors.New(
 			"Tree encoding error: must have at least one feature_name",
-		)
+		))
+	}
+	if tj.FeatureTypes != nil && len(tj.FeatureTypes) != len(tj.Features) {
+		probs = append(probs, errors.New(
+			"Tree encoding error: feature_types and feature_names lengths differ",
+		))
+		// Feature types can not be checked against the nodes.
+		return probs
+	}
+	for _, ft := range tj.FeatureTypes {
+		if ft != featBinary && ft != featBoolean && ft != featReal {
+			probs = append(probs, errors.New(
+				"Tree encoding error: invalid feature_types value",
+			))
+			break
+		}
+	}
+	for _, id := range tj.dupIDs {
+		probs = append(probs, fmt.Errorf(
+			"Tree encoding error: more than one node with id %d",
+			id,
+		))
 	}
 	// Validate nodes
-	for _, node := range tj.Nodes {
+	for _, id := range tj.nodeIDs() {
+		node := tj.Nodes[id]
 		if err := node.Validate(
 			len(tj.Features),
 			len(tj.Nodes),
 			tj.ClassNames,
 		); err != nil {
-			return err
+			probs = append(probs, fmt.Errorf("%w (node %d)", err, id))
+			continue
+		}
+		if node.Type != "internal" {
+			continue
+		}
+		switch ft := tj.featType(node.FeatIdx); {
+		case ft == featReal && node.Threshold == nil:
+			probs = append(probs, fmt.Errorf(
+				"Tree encoding error: missing threshold of real feature"+
+					" split (node %d)",
+				id,
+			))
+		case ft == featBinary && node.Threshold != nil &&
+			!binaryThreshold(*node.Threshold):
+			// Only thresholds in [0, 1) split the values of a binary
+			// feature, any other would be silently ignored.
+			probs = append(probs, fmt.Errorf(
+				"Tree encoding error: threshold %g of binary feature split"+
+					" outside [0, 1) (node %d)",
+				*node.Threshold,
+				id,
+			))
 		}
 	}
-	return nil
+	return append(probs, tj.structureProblems()...)
 }
 
 func (nj nodeJSON) Validate(
@@ -121,3 +192,40 @@ func (nj nodeJSON) Validate(
 	}
 	return nil
 }
+
+// featType returns the type of the feature with index i. Features without a
+// declared type are binary if they are split on at most one threshold, which
+// must lie in [0, 1), and real otherwise.
+func (tj treeJSON) featType(i int) string {
+	if tj.FeatureTypes == nil {
+		ths := tj.thresholds(i)
+		if len(ths) > 1 || len(ths) == 1 && !binaryThreshold(ths[0]) {
+			return featReal
+		}
+		return featBinary
+	}
+	if tj.FeatureTypes[i] == featBoolean {
+		return featBinary
+	}
+	return tj.FeatureTypes[i]
+}
+
+// thresholds returns the distinct thresholds the feature with index i is
+// split on, sorted in increasing order.
+func (tj treeJSON) thresholds(i int) []float64 {
+	var ths []float64
+	for _, n := range tj.Nodes {
+		if n.Type == "internal" && n.FeatIdx == i && n.Threshold != nil &&
+			!slices.Contains(ths, *n.Threshold) {
+			ths = append(ths, *n.Threshold)
+		}
+	}
+	slices.Sort(ths)
+	return ths
+}
+
+// binaryThreshold returns true if th splits the values 0 and 1 of a binary
+// feature.
+func binaryThreshold(th float64) bool {
+	return th >= 0 && th < 1
+}
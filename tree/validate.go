@@ -0,0 +1,128 @@
+package tree
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// Check returns every problem found in the tree encoded in the file passed by
+// path or nil if it can be loaded.
+func Check(path string) []error {
+	_, err := Load(path)
+	if err == nil {
+		return nil
+	}
+	var joined interface{ Unwrap() []error }
+	if errors.As(err, &joined) {
+		return joined.Unwrap()
+	}
+	return []error{err}
+}
+
+// nodeIDs returns the ids of the encoded nodes in increasing order.
+func (tj treeJSON) nodeIDs() []int {
+	ids := make([]int, 0, len(tj.Nodes))
+	for id := range tj.Nodes {
+		ids = append(ids, id)
+	}
+	slices.Sort(ids)
+	return ids
+}
+
+// structureProblems returns the problems that keep the encoded nodes from
+// forming a binary tree rooted at the node with id 0: missing children,
+// cycles, children shared by more than one parent and nodes unreachable from
+// the root. Problems found while traversing the tree report the path of node
+// ids leading to them.
+func (tj treeJSON) structureProblems() []error {
+	if tj.Nodes[0] == nil {
+		return []error{
+			errors.New("Tree structure error: missing root node with id 0"),
+		}
+	}
+
+	type pathElem struct {
+		id   int
+		path []int
+	}
+
+	var probs []error
+	parents := map[int]int{0: -1}
+	toVisit := []pathElem{{id: 0}}
+
+	for len(toVisit) > 0 {
+		el := toVisit[len(toVisit)-1]
+		toVisit = toVisit[:len(toVisit)-1]
+
+		n := tj.Nodes[el.id]
+		if n.Type != "internal" {
+			continue
+		}
+
+		path := append(slices.Clone(el.path), el.id)
+		if n.LeftID == n.RightID {
+			probs = append(probs, fmt.Errorf(
+				"Tree structure error: node %d has the same left and right"+
+					" child (path %s)",
+				el.id,
+				pathString(path),
+			))
+		}
+
+		for _, cid := range []int{n.RightID, n.LeftID} {
+			switch p, seen := parents[cid]; {
+			case cid < 0:
+				// Reported as an invalid node field.
+			case tj.Nodes[cid] == nil:
+				probs = append(probs, fmt.Errorf(
+					"Tree structure error: child %d of node %d does not"+
+						" exist (path %s)",
+					cid,
+					el.id,
+					pathString(path),
+				))
+			case slices.Contains(path, cid):
+				probs = append(probs, fmt.Errorf(
+					"Tree structure error: cycle through node %d (path %s)",
+					cid,
+					pathString(append(slices.Clone(path), cid)),
+				))
+			case seen && p != el.id:
+				probs = append(probs, fmt.Errorf(
+					"Tree structure error: node %d is a child of both node"+
+						" %d and node %d (path %s)",
+					cid,
+					p,
+					el.id,
+					pathString(append(slices.Clone(path), cid)),
+				))
+			case !seen:
+				parents[cid] = el.id
+				toVisit = append(toVisit, pathElem{id: cid, path: path})
+			}
+		}
+	}
+
+	for _, id := range tj.nodeIDs() {
+		if _, ok := parents[id]; !ok {
+			probs = append(probs, fmt.Errorf(
+				"Tree structure error: node %d is unreachable from the root",
+				id,
+			))
+		}
+	}
+
+	return probs
+}
+
+// pathString returns the path of node ids as a string.
+func pathString(path []int) string {
+	s := make([]string, len(path))
+	for i, id := range path {
+		s[i] = strconv.Itoa(id)
+	}
+	return strings.Join(s, " > ")
+}
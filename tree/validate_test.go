@@ -0,0 +1,76 @@
+package tree
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestCheck(t *testing.T) {
+	tBytes := []byte(`{
+		"class_names": ["a", "b"],
+		"feature_names": ["x", "y"],
+		"nodes": {
+			"0": {"id": 0, "type": "internal", "feature_index": 0,
+				"id_left": 1, "id_right": 2},
+			"1": {"id": 1, "type": "internal", "feature_index": 1,
+				"id_left": 0, "id_right": 3},
+			"2": {"id": 2, "type": "internal", "feature_index": 1,
+				"id_left": 3, "id_right": 7},
+			"3": {"id": 3, "type": "leaf", "class": "c"},
+			"4": {"id": 4, "type": "leaf", "class": "a"},
+			"5": {"id": 4, "type": "leaf", "class": "b"}
+		}
+	}`)
+	path, err := writeNewTree(t, tBytes)
+	if err != nil {
+		t.Fatalf("Failed to write tree file: %s", err.Error())
+	}
+
+	expected := []string{
+		"Tree encoding error: more than one node with id 4",
+		"Tree encoding error: invalid node's class (node 3)",
+		"Tree structure error: cycle through node 0 (path 0 > 1 > 0)",
+		"Tree structure error: child 7 of node 2 does not exist (path 0 > 2)",
+		"Tree structure error: node 3 is a child of both node 1 and node 2" +
+			" (path 0 > 2 > 3)",
+		"Tree structure error: node 4 is unreachable from the root",
+	}
+	probs := []string{}
+	for _, p := range Check(path) {
+		probs = append(probs, p.Error())
+	}
+	if !slices.Equal(expected, probs) {
+		t.Errorf(
+			"Problems not equal.\nExpected %q\nbut got  %q",
+			expected,
+			probs,
+		)
+	}
+}
+
+func TestCheck_Valid(t *testing.T) {
+	path, err := writeNewTree(t, forestTest)
+	if err != nil {
+		t.Fatalf("Failed to write tree file: %s", err.Error())
+	}
+	if probs := Check(path); probs != nil {
+		t.Errorf("Expected no problems but got %v", probs)
+	}
+}
+
+func TestCheck_MissingRoot(t *testing.T) {
+	tBytes := []byte(`{
+		"class_names": ["a", "b"],
+		"feature_names": ["x"],
+		"nodes": {"1": {"id": 1, "type": "leaf", "class": "a"}}
+	}`)
+	path, err := writeNewTree(t, tBytes)
+	if err != nil {
+		t.Fatalf("Failed to write tree file: %s", err.Error())
+	}
+	probs := Check(path)
+	if len(probs) != 1 ||
+		probs[0].Error() != "Tree structure error: missing root node with id 0" {
+		t.Errorf("Expected missing root problem but got %v", probs)
+	}
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"text/tabwriter"
+
+	"goexpdt-experiments/tree"
+)
+
+// treeInfo holds the structural statistics of a tree file.
+type treeInfo struct {
+	File string `json:"file"`
+	tree.Stats
+}
+
+// runTreeInfo writes to out the structural statistics of the trees encoded in
+// the files passed by paths, as a json array if asJSON is true and as text
+// otherwise.
+func runTreeInfo(out io.Writer, paths []string, asJSON bool) error {
+	infos := make([]treeInfo, len(paths))
+	for i, fp := range paths {
+		t, err := tree.Load(fp)
+		if err != nil {
+			return fmt.Errorf("Tree %s: %s", fp, err.Error())
+		}
+		infos[i] = treeInfo{File: fp, Stats: t.Stats()}
+	}
+
+	if asJSON {
+		enc := json.NewEncoder(out)
+		enc.SetIndent("", "  ")
+		return enc.Encode(infos)
+	}
+
+	for i, info := range infos {
+		if i > 0 {
+			fmt.Fprintln(out)
+		}
+		if err := writeTreeInfo(out, info); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// writeTreeInfo writes the statistics in info to out as text.
+func writeTreeInfo(out io.Writer, info treeInfo) error {
+	s := info.Stats
+	fmt.Fprintln(out, info.File)
+
+	tw := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(tw, "  features:\t%d (%d used)\n", s.Features, s.FeaturesUsed)
+	fmt.Fprintf(tw, "  nodes:\t%d\n", s.Nodes)
+	fmt.Fprintf(tw, "  depth:\t%d\n", s.Depth)
+	fmt.Fprintf(tw, "  leaves:\t%d\n", s.Leaves)
+	for _, c := range sortedKeys(s.ClassLeaves) {
+		fmt.Fprintf(tw, "    class %s:\t%d\n", c, s.ClassLeaves[c])
+	}
+	fmt.Fprintln(tw, "  leaves by path length:")
+	for _, pl := range s.PathLengths {
+		fmt.Fprintf(tw, "    %d:\t%d\n", pl.Length, pl.Leaves)
+	}
+	fmt.Fprintln(tw, "  splits by feature:")
+	for _, fu := range s.FeatureUsage {
+		fmt.Fprintf(tw, "    %s:\t%d\n", fu.Feature, fu.Splits)
+	}
+	return tw.Flush()
+}
@@ -2,49 +2,69 @@ package main
 
 import (
 	"bufio"
+	"context"
 	"errors"
 	"fmt"
 	"goexpdt-experiments/tree"
+	"math"
 	"math/rand"
 	"os"
+	"slices"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/jtcaraball/goexpdt/compute"
 	"github.com/jtcaraball/goexpdt/query"
 )
 
-const (
-	outputdir = "io/output"
-	solver    = "./kissat"
-)
+const outputdir = "io/output"
+
+// decision holds the outcome of deciding the satisfiability of a formula.
+type decision struct {
+	Sat bool
+	// Witness holds the values of the formula's variables in the solver's
+	// model if it is satisfiable.
+	Witness []query.QConst
+	// Size of the formula's encoding.
+	Size cnfSize
+}
 
-// solveFormula and return ok, const value. ok is false if the formula is
-// unsatisfiable.
+// solveFormula decides the satisfiability of f and returns the values of the
+// variables vs in the solver's model if it is satisfiable. The solver is
+// interrupted if cctx is done.
 func solveFormula(
+	cctx context.Context,
 	f compute.Encodable,
-	v query.QVar,
+	vs []query.QVar,
 	ctx query.QContext,
-	solverPath, cnfPath string,
-) (bool, query.QConst, error) {
-	exitcode, out, err := compute.Step(
-		f,
-		ctx,
-		solverPath,
-		cnfPath,
-	)
+	s satSolver,
+	cnfPath string,
+) (decision, error) {
+	if cctx.Err() != nil {
+		return decision{}, errTimeout
+	}
+
+	size, err := encodeFile(f, ctx, cnfPath)
 	if err != nil {
-		return false, query.QConst{}, err
+		return decision{}, err
 	}
+	out := decision{Size: size}
 
-	if exitcode == 10 {
-		outConst, err := compute.GetValueFromBytes(out, v, ctx)
+	sat, model, err := s.solve(cctx, cnfPath)
+	if err != nil || !sat {
+		return out, err
+	}
+
+	out.Sat = true
+	for _, v := range vs {
+		c, err := s.Parse(model, v, ctx)
 		if err != nil {
-			return false, query.QConst{}, err
+			return decision{}, err
 		}
-		return true, outConst, nil
+		out.Witness = append(out.Witness, c)
 	}
-
-	return false, query.QConst{}, nil
+	return out, nil
 }
 
 // bToC sets the values of c to constant represented in bytes b.
@@ -95,17 +115,57 @@ func sToC(s string, c query.QConst) error {
 	return nil
 }
 
-// randConst the values of c to a random partial instance. If full==true then
-// no values will be set to query.BOT.
-func randConst(c query.QConst, full bool) {
+// randConst the values of c to a random partial instance drawn from r. If
+// full==true then no values will be set to query.BOT. The implications imps
+// between consecutive features, as those of a tree binarized from the
+// thresholds of its real features, are respected: the features of every
+// chain of implications are set from a single value of the original feature,
+// so that the instance is closed under them.
+func randConst(c query.QConst, full bool, imps [][2]int, r *rand.Rand) {
 	limit := 3
 	if full {
 		limit = 2
 	}
 
-	for i := 0; i < len(c.Val); i++ {
-		r := rand.Intn(limit)
-		switch r {
+	// chained[i] is true if feature i implies feature i - 1.
+	chained := make([]bool, len(c.Val))
+	for _, imp := range imps {
+		if imp[1] == imp[0]-1 {
+			chained[imp[0]] = true
+		}
+	}
+
+	for i := 0; i < len(c.Val); {
+		j := i + 1
+		for j < len(c.Val) && chained[j] {
+			j += 1
+		}
+		if j-i > 1 {
+			// Features i to j - 1 have increasing thresholds: they are ONE
+			// below the interval the original value is drawn in and ZERO
+			// above it. Partial instances leave a span of thresholds around
+			// the value undefined.
+			cut := i + r.Intn(j-i+1)
+			lo, hi := cut, cut
+			if !full {
+				lo = i + r.Intn(cut-i+1)
+				hi = cut + r.Intn(j-cut+1)
+			}
+			for k := i; k < j; k++ {
+				switch {
+				case k < lo:
+					c.Val[k] = query.ONE
+				case k >= hi:
+					c.Val[k] = query.ZERO
+				default:
+					c.Val[k] = query.BOT
+				}
+			}
+			i = j
+			continue
+		}
+
+		switch r.Intn(limit) {
 		case 0:
 			c.Val[i] = query.ZERO
 		case 1:
@@ -113,15 +173,21 @@ func randConst(c query.QConst, full bool) {
 		case 2:
 			c.Val[i] = query.BOT
 		}
+		i = j
 	}
 }
 
-// randValConst sets the value of c to a random partial instance with a
-// classification equal to tVal.
-func randValConst(c query.QConst, tVal bool, ctx query.QContext) error {
+// randValConst sets the value of c to a random partial instance drawn from r
+// with a classification equal to tVal.
+func randValConst(
+	c query.QConst,
+	tVal bool,
+	ctx query.QContext,
+	r *rand.Rand,
+) error {
 	match := false
 	for !match {
-		randConst(c, true)
+		randConst(c, true, implications(ctx), r)
 		val, err := evalConst(c, ctx)
 		if err != nil {
 			return err
@@ -131,6 +197,121 @@ func randValConst(c query.QConst, tVal bool, ctx query.QContext) error {
 	return nil
 }
 
+// randValConsts returns n random partial instances drawn from r with a
+// classification equal to tVal.
+func randValConsts(
+	n int,
+	tVal bool,
+	ctx query.QContext,
+	r *rand.Rand,
+) ([]query.QConst, error) {
+	if !hasLeafVal(tVal, ctx) {
+		return nil, fmt.Errorf(
+			"Tree has no leaf classified as %s",
+			targetName(tVal),
+		)
+	}
+	cs := make([]query.QConst, n)
+	for i := range cs {
+		cs[i] = query.AllBotConst(ctx.Dim())
+		if err := randValConst(cs[i], tVal, ctx, r); err != nil {
+			return nil, err
+		}
+	}
+	return cs, nil
+}
+
+// randTargetConsts returns n random partial instances drawn from r for each
+// classification targeted by opts, positive ones first, together with the
+// name of the classification of every instance.
+func randTargetConsts(
+	n int,
+	opts runOpts,
+	ctx query.QContext,
+	r *rand.Rand,
+) ([]query.QConst, []string, error) {
+	var tVals []bool
+	switch opts.Target {
+	case targetPositive:
+		tVals = []bool{true}
+	case targetNegative:
+		tVals = []bool{false}
+	case targetBoth:
+		tVals = []bool{true, false}
+	default:
+		return nil, nil, fmt.Errorf("Unknown target '%s'", opts.Target)
+	}
+
+	var (
+		cs    []query.QConst
+		names []string
+	)
+	for _, tVal := range tVals {
+		tcs, err := randValConsts(n, tVal, ctx, r)
+		if err != nil {
+			return nil, nil, err
+		}
+		cs = append(cs, tcs...)
+		for range tcs {
+			names = append(names, targetName(tVal))
+		}
+	}
+	return cs, names, nil
+}
+
+// randQueryConsts returns the instances a query is evaluated on together with
+// the name of their target classification, as randTargetConsts does. Queries
+// that take no constant, as told by free, are evaluated on n empty instances
+// with no target instead, as random instances would be left unused.
+func randQueryConsts(
+	free bool,
+	n int,
+	opts runOpts,
+	ctx query.QContext,
+	r *rand.Rand,
+) ([]query.QConst, []string, error) {
+	if free {
+		return make([]query.QConst, n), make([]string, n), nil
+	}
+	return randTargetConsts(n, opts, ctx, r)
+}
+
+// constFree returns true if the query built by queryGF takes no constant,
+// that is if it can be built without one. Factories of queries taking a
+// constant fail when given none.
+func constFree(queryGF openOptimQueryGenFactory, ctx query.QContext) bool {
+	_, _, err := queryGF(ctx)
+	return err == nil
+}
+
+// optString returns s as the value of an optional column, missing if s is
+// empty.
+func optString(s string) any {
+	if s == "" {
+		return nil
+	}
+	return s
+}
+
+// targetName returns the Target option value of the classification tVal.
+func targetName(tVal bool) string {
+	if tVal {
+		return targetPositive
+	}
+	return targetNegative
+}
+
+// hasLeafVal returns true if the model in ctx has a leaf with classification
+// tVal. Random instances with a classification no leaf has can not be drawn.
+func hasLeafVal(tVal bool, ctx query.QContext) bool {
+	for _, n := range ctx.Nodes() {
+		if n.IsLeaf() && n.Value == tVal {
+			return true
+		}
+	}
+	return false
+}
+
 // evalConst runs the classification model over c and returns its
 // classification. Returns a non nil error if the constant c is not full or
 // the model in ctx is invalid.
@@ -156,39 +337,159 @@ func evalConst(c query.QConst, ctx query.QContext) (bool, error) {
 }
 
 // genContext returns a query.QContext based on the decision tree encoded in
-// the file represented by its absolute path treePath.
-func genContext(treePath string) (query.QContext, error) {
+// the file represented by its absolute path treePath, with positive as the
+// tree's positive class. The tree's own positive class is kept if positive is
+// empty.
+func genContext(treePath, positive string) (query.QContext, error) {
 	t, err := tree.Load(treePath)
 	if err != nil {
 		return nil, err
 	}
-	ctx := query.BasicQContext(&t)
-	return ctx, nil
+	if positive != "" {
+		if err = t.SetPositive(positive); err != nil {
+			return nil, err
+		}
+	}
+	return treeQContext(&t), nil
+}
+
+// shapedModel is a model reporting its structure, as trees do.
+type shapedModel interface {
+	Depth() int
+	PathLengths() []int
+}
+
+// treeShape returns the depth and number of leaves of the tree in ctx if
+// opts.TreeStats is set, as they are only written then, and zero otherwise or
+// if the model does not report them.
+func treeShape(ctx query.QContext, opts runOpts) (int, int) {
+	tc, ok := ctx.(*treeContext)
+	if !opts.TreeStats || !ok {
+		return 0, 0
+	}
+	sm, ok := tc.model.(shapedModel)
+	if !ok {
+		return 0, 0
+	}
+	leaves := 0
+	for _, n := range sm.PathLengths() {
+		leaves += n
+	}
+	return sm.Depth(), leaves
+}
+
+// targetClasses returns the positive classes an experiment must be run with
+// over the tree encoded in the file treePath according to opts.Positive:
+// every class of the tree if it equals allClasses, the tree's own positive
+// class if it is empty and opts.Positive otherwise.
+func targetClasses(treePath string, opts runOpts) ([]string, error) {
+	t, err := tree.Load(treePath)
+	if err != nil {
+		return nil, err
+	}
+	switch opts.Positive {
+	case allClasses:
+		return t.Classes(), nil
+	case "":
+		return []string{t.Positive()}, nil
+	default:
+		if !slices.Contains(t.Classes(), opts.Positive) {
+			return nil, fmt.Errorf(
+				"Class '%s' does not exist in tree %s",
+				opts.Positive,
+				treePath,
+			)
+		}
+		return []string{opts.Positive}, nil
+	}
 }
 
-// parseTIInput returns instances and context represented in the tree-instance
-// input file passed by path.
-func parseTIInput(inf string) ([]query.QConst, query.QContext, error) {
+// parseTIInput returns the tree file path, instances and context represented
+// in the tree-instance input file passed by path, with positive as the tree's
+// positive class (see genContext). Instances are either words
+// in the {0, 1, _} alphabet over the tree's features or comma separated real
+// values over the tree's original features, which are binarized using the
+// tree's thresholds.
+func parseTIInput(
+	inf, positive string,
+) (string, []query.QConst, query.QContext, error) {
 	treeFP, instStrings, err := scanTIFile(inf)
 	if err != nil {
-		return nil, nil, err
+		return "", nil, nil, err
 	}
 
-	ctx, err := genContext(treeFP)
+	t, err := tree.Load(treeFP)
 	if err != nil {
-		return nil, nil, err
+		return "", nil, nil, err
 	}
+	if positive != "" {
+		if err = t.SetPositive(positive); err != nil {
+			return "", nil, nil, err
+		}
+	}
+	ctx := treeQContext(&t)
 
 	instances := make([]query.QConst, len(instStrings))
 	for i, cb := range instStrings {
-		instances[i] = query.AllBotConst(ctx.Dim())
-		err := sToC(cb, instances[i])
-		if err != nil {
-			return nil, nil, err
+		if instances[i], err = parseInstance(cb, &t); err != nil {
+			return "", nil, nil, err
+		}
+	}
+
+	return treeFP, instances, ctx, nil
+}
+
+// binarizer is a model that maps real valued instances over its original
+// features to partial instances of its features.
+type binarizer interface {
+	Dim() int
+	BinarizeInstance(x []float64) (query.QConst, error)
+	Implications() [][2]int
+}
+
+// parseInstance returns the instance of the model m represented as s, either
+// a word in the {0, 1, _} alphabet over the model's features or comma
+// separated real values over its original features. Words must respect the
+// implications between the model's features and are closed under them.
+func parseInstance(s string, m binarizer) (query.QConst, error) {
+	if !strings.Contains(s, ",") {
+		c := query.AllBotConst(m.Dim())
+		if err := sToC(s, c); err != nil {
+			return query.QConst{}, err
 		}
+		c, ok := closedConst(c, m.Implications())
+		if !ok {
+			return query.QConst{}, errors.New(
+				"Instance contradicts the order of the thresholds of a feature",
+			)
+		}
+		return c, nil
+	}
+	x, err := sToReal(s)
+	if err != nil {
+		return query.QConst{}, err
 	}
+	return m.BinarizeInstance(x)
+}
 
-	return instances, ctx, nil
+// sToReal returns the real valued instance represented as s, a comma
+// separated list of values where _ or NaN denote a missing value.
+func sToReal(s string) ([]float64, error) {
+	vals := strings.Split(s, ",")
+	x := make([]float64, len(vals))
+	for i, v := range vals {
+		v = strings.TrimSpace(v)
+		if v == "_" {
+			x[i] = math.NaN()
+			continue
+		}
+		f, err := strconv.ParseFloat(v, 64)
+		if err != nil {
+			return nil, fmt.Errorf("Invalid real feature value in index %d", i)
+		}
+		x[i] = f
+	}
+	return x, nil
 }
 
 // scanTIFIle scans a tree/instance input file by path. Returns its tree file
@@ -229,3 +530,13 @@ func dateTimeAsString(t time.Time) string {
 
 	return fmt.Sprintf("%d-%d-%d_%d:%d:%d", y, int(m), d, h, min, s)
 }
+
+// writeOutput writes b to the file at path, creating or truncating it, or to
+// stdout if path is empty.
+func writeOutput(path string, b []byte) error {
+	if path == "" {
+		_, err := os.Stdout.Write(b)
+		return err
+	}
+	return os.WriteFile(path, b, 0o644)
+}
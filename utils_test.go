@@ -0,0 +1,87 @@
+package main
+
+import (
+	"math/rand"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/jtcaraball/goexpdt/query"
+)
+
+func TestRandQueryConsts(t *testing.T) {
+	// A tree without positive leaves has no positive instance to draw.
+	ctx := treeQContext(thresholdModel{
+		nodes: []query.Node{
+			{Feat: 0, ZChild: 1, OChild: 2},
+			{Value: false, ZChild: -1, OChild: -1},
+			{Value: false, ZChild: -1, OChild: -1},
+		},
+	})
+	opts := runOpts{Target: targetPositive}
+	r := rand.New(rand.NewSource(0))
+
+	if !constFree(DFS_LL_O, ctx) {
+		t.Errorf("Expected DFS under LL to take no constant")
+	}
+	if constFree(SR_LL_O, ctx) {
+		t.Errorf("Expected SR under LL to take a constant")
+	}
+
+	inst, targets, err := randQueryConsts(true, 3, opts, ctx, r)
+	if err != nil {
+		t.Fatalf("Failed to get instances: %s", err.Error())
+	}
+	if len(inst) != 3 || len(targets) != 3 {
+		t.Fatalf("Expected 3 instances but got %d", len(inst))
+	}
+	for i := range inst {
+		if inst[i].Val != nil || targets[i] != "" {
+			t.Errorf(
+				"Expected no instance drawn but got %s (%s)",
+				inst[i].AsString(),
+				targets[i],
+			)
+		}
+	}
+
+	if _, _, err = randQueryConsts(false, 3, opts, ctx, r); err == nil {
+		t.Errorf("Expected error drawing positive instances")
+	}
+}
+
+func TestTreeShape(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "tree.json")
+	tBytes := []byte(`{
+		"class_names": ["no", "yes"],
+		"feature_names": ["x", "y"],
+		"nodes": {
+			"0": {"id": 0, "type": "internal", "feature_index": 0,
+				"id_left": 1, "id_right": 2},
+			"1": {"id": 1, "type": "leaf", "class": "no"},
+			"2": {"id": 2, "type": "internal", "feature_index": 1,
+				"id_left": 3, "id_right": 4},
+			"3": {"id": 3, "type": "leaf", "class": "no"},
+			"4": {"id": 4, "type": "leaf", "class": "yes"}
+		}
+	}`)
+	if err := os.WriteFile(path, tBytes, 0o644); err != nil {
+		t.Fatalf("Failed to write tree file: %s", err.Error())
+	}
+	ctx, err := genContext(path, "")
+	if err != nil {
+		t.Fatalf("Failed to load tree: %s", err.Error())
+	}
+
+	if depth, leaves := treeShape(ctx, runOpts{}); depth != 0 || leaves != 0 {
+		t.Errorf(
+			"Expected no shape without tree stats but got %d, %d",
+			depth,
+			leaves,
+		)
+	}
+	depth, leaves := treeShape(ctx, runOpts{TreeStats: true})
+	if depth != 2 || leaves != 3 {
+		t.Errorf("Expected depth 2 and 3 leaves but got %d, %d", depth, leaves)
+	}
+}
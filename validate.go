@@ -0,0 +1,32 @@
+package main
+
+import (
+	"fmt"
+	"io"
+
+	"goexpdt-experiments/tree"
+)
+
+// runValidate checks the tree files passed by paths, writing to out every
+// problem found in each of them, and returns the number of files that can not
+// be loaded.
+func runValidate(out io.Writer, paths []string) int {
+	invalid := 0
+	for _, fp := range paths {
+		probs := tree.Check(fp)
+		if len(probs) == 0 {
+			fmt.Fprintf(out, "%s: ok\n", fp)
+			continue
+		}
+		invalid += 1
+		if len(probs) == 1 {
+			fmt.Fprintf(out, "%s: 1 problem\n", fp)
+		} else {
+			fmt.Fprintf(out, "%s: %d problems\n", fp, len(probs))
+		}
+		for _, p := range probs {
+			fmt.Fprintf(out, "  - %s\n", p.Error())
+		}
+	}
+	return invalid
+}
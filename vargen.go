@@ -25,3 +25,13 @@ func varGenHammingDistance(v1, v2 query.QVar) query.QVar {
 	}
 	return query.QVar("hdist" + sep + string(v2) + sep + string(v1))
 }
+
+// varGenEqualFeature returns a variable equal to the sorted concatenation of
+// variables v1 and v2 with the addition of the prefix "eqf" separated using
+// the record separator character (ascii 30).
+func varGenEqualFeature(v1, v2 query.QVar) query.QVar {
+	if string(v1) < string(v2) {
+		return query.QVar("eqf" + sep + string(v1) + sep + string(v2))
+	}
+	return query.QVar("eqf" + sep + string(v2) + sep + string(v1))
+}